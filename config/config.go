@@ -19,6 +19,10 @@ import (
 type Config struct {
 	Server Server `yaml:"server"`
 	Feeds  []Feed `yaml:"feeds"`
+
+	// RuleSets are named lists of rules shared across feeds, referenced with
+	// `use`. Parse expands them into the feeds' rules.
+	RuleSets map[string][]Rule `yaml:"rule_sets,omitempty"`
 }
 
 type Server struct {
@@ -35,12 +39,115 @@ type Feed struct {
 	ID     string `yaml:"id"`
 	Source string `yaml:"source"`
 	Rules  []Rule `yaml:"rules"`
+
+	// Use lists rule sets whose rules run before the feed's own rules.
+	Use []string `yaml:"use,omitempty"`
+
+	// Mode is how rules combine: all (default) keeps items matched by every
+	// rule; first_match lets the first matching rule decide through its
+	// action, and DefaultAction (keep or drop, default keep) decide the items
+	// no rule matched.
+	Mode          string `yaml:"mode,omitempty"`
+	DefaultAction string `yaml:"default_action,omitempty"`
+
+	// OnRuleError decides what happens to an item when a rule can't be
+	// evaluated on it (missing or unparseable data): keep (default), drop,
+	// or fail the whole request.
+	OnRuleError string `yaml:"on_rule_error,omitempty"`
+
+	// Timezone is the IANA zone (e.g. Europe/Paris) in which calendar rules
+	// such as weekday_in read publication dates. Defaults to UTC.
+	Timezone string `yaml:"timezone,omitempty"`
+
+	// EpisodePattern is the regex extracting episode numbers from titles when
+	// <itunes:episode> is missing, with the number in a group named episode,
+	// e.g. `S\d+E(?P<episode>\d+)`. Defaults to a 3 or 4 digit number.
+	EpisodePattern string `yaml:"episode_pattern,omitempty"`
 }
 
 type Rule struct {
-	Type  string `yaml:"type"`
-	Min   int    `yaml:"min,omitempty"`
-	Value string `yaml:"value,omitempty"`
+	Type   string   `yaml:"type"`
+	Min    int      `yaml:"min,omitempty"`
+	Max    int      `yaml:"max,omitempty"`
+	Value  string   `yaml:"value,omitempty"`
+	Values []string `yaml:"values,omitempty"`
+
+	// Action is what a matching rule does to an item in first_match mode:
+	// keep or drop.
+	Action string `yaml:"action,omitempty"`
+
+	// Missing decides the fate of items a rule has no data for, for rules
+	// supporting it (episode number rules): keep or drop (default).
+	Missing string `yaml:"missing,omitempty"`
+
+	// CaseSensitive, FoldAccents and Normalize tune string-matching rules.
+	// CaseSensitive is a pointer so that each rule type keeps its own default
+	// (contains/excludes/category rules ignore case, regex rules don't).
+	// FoldAccents matches "invité" with "invite". Normalize is the Unicode
+	// form both sides are converted to before comparing: nfc (default) or nfd.
+	CaseSensitive *bool  `yaml:"case_sensitive,omitempty"`
+	FoldAccents   bool   `yaml:"fold_accents,omitempty"`
+	Normalize     string `yaml:"normalize,omitempty"`
+
+	// Path addresses an item child element or attribute for field rules,
+	// e.g. "podcast:transcript@type".
+	Path string `yaml:"path,omitempty"`
+
+	// From and To bound range rules such as length_between.
+	From string `yaml:"from,omitempty"`
+	To   string `yaml:"to,omitempty"`
+
+	// Count and By configure feed-level rules (keep_latest, skip_oldest):
+	// how many items, ordered by pub_date (default) or episode.
+	Count int    `yaml:"count,omitempty"`
+	By    string `yaml:"by,omitempty"`
+
+	// Key and Keep configure the dedupe rule: the identity of an episode
+	// (guid, enclosure_url or normalized_title) and which copy to keep
+	// (first in feed order, or latest by pubDate).
+	Key  string `yaml:"key,omitempty"`
+	Keep string `yaml:"keep,omitempty"`
+
+	// Markers lists the multipart marker patterns used by title_fraction_equals
+	// and multipart_complete: built-in names or regexes with part/total groups.
+	Markers []string `yaml:"markers,omitempty"`
+
+	// All, Any and Not turn the rule into a boolean group of nested rules.
+	// A group rule has no Type:
+	//   - all: every nested rule must match
+	//   - any: at least one nested rule must match
+	//   - not: the nested rule must not match
+	All []Rule `yaml:"all,omitempty"`
+	Any []Rule `yaml:"any,omitempty"`
+	Not *Rule  `yaml:"not,omitempty"`
+
+	// Expr is a boolean expression over the item fields, used instead of Type,
+	// e.g. `episode >= 640 && !contains(title, "REDIFF")`.
+	Expr string `yaml:"expr,omitempty"`
+
+	// Use splices the rules of the named rule sets in place of this rule,
+	// e.g. `- use: [no-reruns]`. Parse expands it.
+	Use []string `yaml:"use,omitempty"`
+
+	// Origin is where Parse found the rule in the configuration, e.g.
+	// `rules[2].any[0]`, or `rule_sets["no-reruns"][1]` for a rule spliced
+	// from a rule set. Rule errors and explanations refer to it.
+	Origin string `yaml:"-"`
+
+	// Node is the YAML mapping the rule was loaded from, kept so that rule
+	// types registered by library users can read their own options; built-in
+	// rule types read the fields above. It is nil for rules built in code.
+	Node *yaml.Node `yaml:"-"`
+}
+
+// UnmarshalYAML decodes a rule and keeps its YAML node.
+func (r *Rule) UnmarshalYAML(node *yaml.Node) error {
+	type plain Rule
+	if err := node.Decode((*plain)(r)); err != nil {
+		return err
+	}
+	r.Node = node
+	return nil
 }
 
 func Load(path string) Config {
@@ -49,10 +156,23 @@ func Load(path string) Config {
 		log.Fatal(err)
 	}
 
-	var cfg Config
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
-		log.Fatal(err)
+	cfg, err := Parse(data)
+	if err != nil {
+		log.Fatalf("%s: %v", path, err)
 	}
 
 	return cfg
 }
+
+// Parse decodes a YAML configuration and expands the rule sets used by
+// its feeds.
+func Parse(data []byte) (Config, error) {
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return Config{}, err
+	}
+	if err := cfg.expandRuleSets(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -39,3 +40,145 @@ feeds:
 		t.Fatalf("unexpected feed id: %q", cfg.Feeds[0].ID)
 	}
 }
+
+func TestLoadParsesNestedRuleGroups(t *testing.T) {
+	yaml := `
+feeds:
+  - id: legend
+    source: https://feeds.example.com/feed.rss
+    rules:
+      - any:
+          - type: title_contains
+            value: "[1/2]"
+          - type: title_contains
+            value: "[2/2]"
+      - not:
+          type: title_contains
+          value: "[REDIFF]"
+`
+
+	f, err := os.CreateTemp("", "rss-proxy-config-*.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(yaml); err != nil {
+		_ = f.Close()
+		t.Fatal(err)
+	}
+	_ = f.Close()
+
+	cfg := Load(f.Name())
+	rules := cfg.Feeds[0].Rules
+	if len(rules) != 2 {
+		t.Fatalf("expected 2 rules, got %d", len(rules))
+	}
+	if len(rules[0].Any) != 2 || rules[0].Any[1].Value != "[2/2]" {
+		t.Fatalf("unexpected any group: %+v", rules[0].Any)
+	}
+	if rules[1].Not == nil || rules[1].Not.Value != "[REDIFF]" {
+		t.Fatalf("unexpected not group: %+v", rules[1].Not)
+	}
+}
+
+func TestParseExpandsRuleSets(t *testing.T) {
+	cfg, err := Parse([]byte(`
+rule_sets:
+  no-reruns:
+    - type: title_excludes
+      value: REDIFF
+    - type: title_excludes
+      value: best of
+  clean:
+    - use: [no-reruns]
+    - type: episode_type
+      value: full
+feeds:
+  - id: legend
+    source: https://feeds.example.com/feed.rss
+    use: [clean]
+    rules:
+      - type: episode_number_min
+        min: 640
+      - not:
+          use: [no-reruns]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	feed := cfg.Feeds[0]
+	if feed.Use != nil {
+		t.Fatalf("use should be cleared after expansion, got %v", feed.Use)
+	}
+	var types []string
+	for _, r := range feed.Rules {
+		types = append(types, r.Type)
+	}
+	want := "title_excludes,title_excludes,episode_type,episode_number_min,"
+	if got := strings.Join(types, ","); got != want {
+		t.Fatalf("got rule types %q, want %q", got, want)
+	}
+	not := feed.Rules[4].Not
+	if not == nil || len(not.All) != 2 || not.All[1].Value != "best of" {
+		t.Fatalf("unexpected not group: %+v", not)
+	}
+
+	// Rules keep where they were configured.
+	origins := []string{
+		`rule_sets["no-reruns"][0]`,
+		`rule_sets["no-reruns"][1]`,
+		`rule_sets["clean"][1]`,
+		`rules[0]`,
+		`rules[1]`,
+	}
+	for i, want := range origins {
+		if got := feed.Rules[i].Origin; got != want {
+			t.Fatalf("rules[%d]: origin %q, want %q", i, got, want)
+		}
+	}
+	if not.Origin != "rules[1].not" || not.All[1].Origin != `rule_sets["no-reruns"][1]` {
+		t.Fatalf("unexpected not group origins: %q, %q", not.Origin, not.All[1].Origin)
+	}
+}
+
+func TestParseRejectsBadRuleSets(t *testing.T) {
+	cases := map[string]string{
+		`rule set "a": rule set "b": cycle: a -> b -> a`: `
+rule_sets:
+  a: [{use: [b]}]
+  b: [{use: [a]}]
+`,
+		`feed "legend": unknown rule set "missing"`: `
+feeds:
+  - id: legend
+    use: [missing]
+`,
+		`feed "legend": rules[0].not: rule set "empty" has no rules`: `
+rule_sets:
+  empty: []
+feeds:
+  - id: legend
+    rules:
+      - not: {use: [empty]}
+`,
+		`rule set "b": rule_sets["b"][0].any: rule sets "empty", "none" have no rules`: `
+rule_sets:
+  empty: []
+  none: []
+  b:
+    - any: [{use: [empty]}, {use: [none]}]
+`,
+		`rule set "a": a use rule can't also have a type`: `
+rule_sets:
+  a: [{use: [b], type: title_contains}]
+  b: []
+`,
+	}
+	for want, yaml := range cases {
+		_, err := Parse([]byte(yaml))
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected error containing %q, got %v", want, err)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// expandRuleSets replaces every `use` reference, in feeds and in rule sets,
+// with the rules of the referenced sets. Every set is expanded, used or not,
+// so that mistakes are reported at startup.
+func (c *Config) expandRuleSets() error {
+	names := make([]string, 0, len(c.RuleSets))
+	for name := range c.RuleSets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, err := c.ruleSet(name, nil); err != nil {
+			return err
+		}
+	}
+
+	for i := range c.Feeds {
+		feed := &c.Feeds[i]
+
+		var rules []Rule
+		for _, name := range feed.Use {
+			set, err := c.ruleSet(name, nil)
+			if err != nil {
+				return fmt.Errorf("feed %q: %w", feed.ID, err)
+			}
+			rules = append(rules, set...)
+		}
+		own, err := c.expandRules(feed.Rules, "rules", nil)
+		if err != nil {
+			return fmt.Errorf("feed %q: %w", feed.ID, err)
+		}
+		feed.Rules = append(rules, own...)
+		feed.Use = nil
+	}
+	return nil
+}
+
+// ruleSet returns the expanded rules of the named set. stack lists the sets
+// being expanded, to detect cycles.
+func (c *Config) ruleSet(name string, stack []string) ([]Rule, error) {
+	for i, s := range stack {
+		if s == name {
+			cycle := append(append([]string{}, stack[i:]...), name)
+			return nil, fmt.Errorf("cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+	rules, ok := c.RuleSets[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown rule set %q", name)
+	}
+
+	stack = append(append([]string{}, stack...), name)
+	out, err := c.expandRules(rules, fmt.Sprintf("rule_sets[%q]", name), stack)
+	if err != nil {
+		return nil, fmt.Errorf("rule set %q: %w", name, err)
+	}
+	return out, nil
+}
+
+// expandRules expands the `use` entries of rules, including inside groups.
+// path locates rules in the configuration, e.g. `rules` or
+// `rule_sets["no-reruns"]`.
+func (c *Config) expandRules(rules []Rule, path string, stack []string) ([]Rule, error) {
+	var out []Rule
+	for i, r := range rules {
+		expanded, err := c.expandRule(r, fmt.Sprintf("%s[%d]", path, i), stack)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, expanded...)
+	}
+	return out, nil
+}
+
+// expandRule expands a rule found at origin in the configuration, and sets
+// its Origin. Rules spliced from a set keep the Origin they have there.
+func (c *Config) expandRule(r Rule, origin string, stack []string) ([]Rule, error) {
+	if r.Use != nil {
+		if r.Type != "" || r.All != nil || r.Any != nil || r.Not != nil || r.Expr != "" {
+			return nil, errors.New("a use rule can't also have a type, group or expr")
+		}
+		var out []Rule
+		for _, name := range r.Use {
+			set, err := c.ruleSet(name, stack)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, set...)
+		}
+		return out, nil
+	}
+
+	r.Origin = origin
+	var err error
+	if r.All != nil {
+		if r.All, err = c.expandGroup(r.All, origin+".all", stack); err != nil {
+			return nil, err
+		}
+	}
+	if r.Any != nil {
+		if r.Any, err = c.expandGroup(r.Any, origin+".any", stack); err != nil {
+			return nil, err
+		}
+	}
+	if r.Not != nil {
+		nested, err := c.expandRule(*r.Not, origin+".not", stack)
+		if err != nil {
+			return nil, err
+		}
+		if len(nested) == 0 {
+			return nil, emptySetsError(origin+".not", []Rule{*r.Not})
+		}
+		// A set used under not may hold several rules: negate them as a
+		// whole.
+		if len(nested) == 1 {
+			r.Not = &nested[0]
+		} else {
+			r.Not = &Rule{All: nested, Origin: origin + ".not"}
+		}
+	}
+	return []Rule{r}, nil
+}
+
+// expandGroup expands the rules of an all or any group, which must not end up
+// empty because of the sets it uses.
+func (c *Config) expandGroup(rules []Rule, path string, stack []string) ([]Rule, error) {
+	out, err := c.expandRules(rules, path, stack)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) == 0 && len(rules) > 0 {
+		return nil, emptySetsError(path, rules)
+	}
+	return out, nil
+}
+
+// emptySetsError reports a group left without rules by the sets its rules use.
+func emptySetsError(path string, rules []Rule) error {
+	var names []string
+	for _, r := range rules {
+		for _, name := range r.Use {
+			names = append(names, strconv.Quote(name))
+		}
+	}
+	if len(names) == 1 {
+		return fmt.Errorf("%s: rule set %s has no rules", path, names[0])
+	}
+	return fmt.Errorf("%s: rule sets %s have no rules", path, strings.Join(names, ", "))
+}
@@ -2,4 +2,7 @@ module rss-proxy
 
 go 1.25
 
-require gopkg.in/yaml.v3 v3.0.1
+require (
+	golang.org/x/text v0.30.0
+	gopkg.in/yaml.v3 v3.0.1
+)
@@ -3,6 +3,9 @@ package main
 import (
 	"log"
 	"net/http"
+	"time"
+	// Embedded zone database for feed timezones: the runtime image has none.
+	_ "time/tzdata"
 
 	"rss-proxy/config"
 	"rss-proxy/rss"
@@ -12,7 +15,10 @@ func main() {
 	cfg := config.Load("config.yml")
 
 	for _, feed := range cfg.Feeds {
-		handler := rss.NewHandlerWithDefaultCacheAndBaseURL(feed, cfg.Server.BaseURL)
+		handler, err := rss.NewFeedHandler(feed, rss.NewHTTPCache(15*time.Minute), cfg.Server.BaseURL)
+		if err != nil {
+			log.Fatal(err)
+		}
 		http.Handle("/rss/"+feed.ID+".xml", handler)
 	}
 
@@ -0,0 +1,94 @@
+package rss
+
+import (
+	"strings"
+
+	"rss-proxy/config"
+)
+
+// itemKeywords returns the <itunes:keywords> entries, trimmed.
+func itemKeywords(item Item) []string {
+	var out []string
+	for _, k := range strings.Split(item.Keywords, ",") {
+		if k = strings.TrimSpace(k); k != "" {
+			out = append(out, k)
+		}
+	}
+	return out
+}
+
+// categorySet is a compiled list of categories, compared with the rule text
+// options (case-insensitive by default).
+type categorySet struct {
+	opts   textOptions
+	values map[string]bool
+}
+
+func compileCategorySet(rule config.Rule) (categorySet, error) {
+	values, err := ruleValues(rule)
+	if err != nil {
+		return categorySet{}, err
+	}
+	opts, err := compileTextOptions(rule, false)
+	if err != nil {
+		return categorySet{}, err
+	}
+	set := categorySet{opts: opts, values: make(map[string]bool, len(values))}
+	for _, v := range values {
+		set.values[opts.prepare(v)] = true
+	}
+	return set, nil
+}
+
+// hasCategory reports whether one of the item <category> values is in set.
+func hasCategory(item Item, set categorySet) bool {
+	for _, c := range item.Categories {
+		if set.values[set.opts.prepare(strings.TrimSpace(c))] {
+			return true
+		}
+	}
+	return false
+}
+
+func compileCategoryIn(rule config.Rule) (Matcher, error) {
+	// Keep items with at least one of the listed categories.
+	set, err := compileCategorySet(rule)
+	if err != nil {
+		return nil, err
+	}
+	return func(item Item, _ *EvalContext) (bool, error) {
+		return hasCategory(item, set), nil
+	}, nil
+}
+
+func compileCategoryNotIn(rule config.Rule) (Matcher, error) {
+	// Drop items with any of the listed categories.
+	set, err := compileCategorySet(rule)
+	if err != nil {
+		return nil, err
+	}
+	return func(item Item, _ *EvalContext) (bool, error) {
+		return !hasCategory(item, set), nil
+	}, nil
+}
+
+func compileKeywordContains(rule config.Rule) (Matcher, error) {
+	// Keep items with a keyword containing the value (case-insensitive
+	// unless case_sensitive is set).
+	if err := requireValue(rule); err != nil {
+		return nil, err
+	}
+	opts, err := compileTextOptions(rule, false)
+	if err != nil {
+		return nil, err
+	}
+	value := opts.prepare(strings.TrimSpace(rule.Value))
+	return func(item Item, _ *EvalContext) (bool, error) {
+		for _, k := range itemKeywords(item) {
+			if strings.Contains(opts.prepare(k), value) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}, nil
+}
@@ -0,0 +1,66 @@
+package rss
+
+import (
+	"testing"
+
+	"rss-proxy/config"
+)
+
+const networkRSS = `
+<rss xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd">
+  <channel>
+    <title>Network</title>
+    <item>
+      <title>Morning 1</title>
+      <category>Morning Show</category>
+      <category>News</category>
+      <itunes:keywords>politique, économie</itunes:keywords>
+    </item>
+    <item>
+      <title>Sport 1</title>
+      <category domain="shows">sport</category>
+      <itunes:keywords>Football,Rugby</itunes:keywords>
+    </item>
+    <item>
+      <title>Untagged</title>
+    </item>
+  </channel>
+</rss>
+`
+
+func TestParseCategoriesAndKeywords(t *testing.T) {
+	feed, err := Parse([]byte(networkRSS))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := feed.Channel.Items[0]
+	if len(first.Categories) != 2 || first.Categories[1] != "News" {
+		t.Fatalf("unexpected categories: %v", first.Categories)
+	}
+	if kw := itemKeywords(first); len(kw) != 2 || kw[1] != "économie" {
+		t.Fatalf("unexpected keywords: %v", kw)
+	}
+}
+
+func TestCategoryAndKeywordRules(t *testing.T) {
+	feed, err := Parse([]byte(networkRSS))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := ApplyRules(feed, []config.Rule{{Type: "category_in", Values: []string{"morning show", "SPORT"}}})
+	if len(out.Channel.Items) != 2 {
+		t.Fatalf("expected 2 items kept, got %+v", out.Channel.Items)
+	}
+
+	out = ApplyRules(feed, []config.Rule{{Type: "category_not_in", Value: "sport"}})
+	if len(out.Channel.Items) != 2 || out.Channel.Items[1].Title != "Untagged" {
+		t.Fatalf("expected Morning 1 and Untagged, got %+v", out.Channel.Items)
+	}
+
+	out = ApplyRules(feed, []config.Rule{{Type: "keyword_contains", Value: "rug"}})
+	if len(out.Channel.Items) != 1 || out.Channel.Items[0].Title != "Sport 1" {
+		t.Fatalf("expected only Sport 1, got %+v", out.Channel.Items)
+	}
+}
@@ -0,0 +1,299 @@
+package rss
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"rss-proxy/config"
+)
+
+// pubDateLayouts lists the RFC 822 variants found in real-world <pubDate> values,
+// tried in order once the optional weekday has been stripped.
+var pubDateLayouts = []string{
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04 -0700",
+	"2 Jan 06 15:04:05 -0700",
+	"2 Jan 06 15:04 -0700",
+	"2 January 2006 15:04:05 -0700",
+	"2 January 2006 15:04 -0700",
+	"2 Jan 2006 15:04:05",
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// rfc822Zones maps the zone names allowed by RFC 822 to numeric offsets.
+// time.Parse would otherwise accept them with a zero offset.
+var rfc822Zones = map[string]string{
+	"UT":   "+0000",
+	"UTC":  "+0000",
+	"GMT":  "+0000",
+	"Z":    "+0000",
+	"EST":  "-0500",
+	"EDT":  "-0400",
+	"CST":  "-0600",
+	"CDT":  "-0500",
+	"MST":  "-0700",
+	"MDT":  "-0600",
+	"PST":  "-0800",
+	"PDT":  "-0700",
+	"CET":  "+0100",
+	"CEST": "+0200",
+}
+
+var (
+	reSpaces  = regexp.MustCompile(`\s+`)
+	reWeekday = regexp.MustCompile(`^[A-Za-z]+,\s*`)
+)
+
+// parsePubDate parses an RSS <pubDate> value.
+//
+// It accepts RFC 822 / RFC 1123 dates with or without weekday or seconds,
+// two- or four-digit years, numeric offsets and RFC 822 zone names,
+// as well as RFC 3339 and plain YYYY-MM-DD dates. Dates without a zone are UTC.
+func parsePubDate(s string) (time.Time, error) {
+	s = strings.TrimSpace(reSpaces.ReplaceAllString(s, " "))
+	if s == "" {
+		return time.Time{}, errors.New("no pubDate provided")
+	}
+
+	// Weekday names are optional and often wrong: never validate them.
+	s = reWeekday.ReplaceAllString(s, "")
+
+	// Replace a trailing zone name with its numeric offset.
+	if i := strings.LastIndexByte(s, ' '); i >= 0 {
+		if off, ok := rfc822Zones[strings.ToUpper(s[i+1:])]; ok {
+			s = s[:i+1] + off
+		}
+	}
+
+	for _, layout := range pubDateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("can't parse pubDate %q", s)
+}
+
+// parseAge parses a duration like "90d", "2w", "36h" or "1h30m".
+//
+// It extends time.ParseDuration with the d (24h) and w (7d) units.
+func parseAge(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, errors.New("empty duration")
+	}
+
+	var total time.Duration
+	rest := s
+	for rest != "" {
+		i := 0
+		for i < len(rest) && (rest[i] >= '0' && rest[i] <= '9' || rest[i] == '.') {
+			i++
+		}
+		j := i
+		for j < len(rest) && rest[j] != '.' && (rest[j] < '0' || rest[j] > '9') {
+			j++
+		}
+		if i == 0 || j == i {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+
+		num, unit := rest[:i], rest[i:j]
+		rest = rest[j:]
+
+		switch unit {
+		case "d", "w":
+			n, err := strconv.ParseFloat(num, 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration %q", s)
+			}
+			day := 24 * time.Hour
+			if unit == "w" {
+				day *= 7
+			}
+			total += time.Duration(n * float64(day))
+		default:
+			d, err := time.ParseDuration(num + unit)
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration %q", s)
+			}
+			total += d
+		}
+	}
+
+	if total <= 0 {
+		return 0, fmt.Errorf("duration %q must be positive", s)
+	}
+	return total, nil
+}
+
+func compilePublishedAfter(rule config.Rule) (Matcher, error) {
+	// Keep items published at or after the configured date.
+	if err := requireValue(rule); err != nil {
+		return nil, err
+	}
+	limit, err := parsePubDate(rule.Value)
+	if err != nil {
+		return nil, err
+	}
+	return func(item Item, _ *EvalContext) (bool, error) {
+		t, err := parsePubDate(item.PubDate)
+		if err != nil {
+			return false, err
+		}
+		return !t.Before(limit), nil
+	}, nil
+}
+
+func compilePublishedBefore(rule config.Rule) (Matcher, error) {
+	// Keep items published strictly before the configured date.
+	if err := requireValue(rule); err != nil {
+		return nil, err
+	}
+	limit, err := parsePubDate(rule.Value)
+	if err != nil {
+		return nil, err
+	}
+	return func(item Item, _ *EvalContext) (bool, error) {
+		t, err := parsePubDate(item.PubDate)
+		if err != nil {
+			return false, err
+		}
+		return t.Before(limit), nil
+	}, nil
+}
+
+func compileMaxAge(rule config.Rule) (Matcher, error) {
+	// Keep items published no longer than the configured age ago.
+	if err := requireValue(rule); err != nil {
+		return nil, err
+	}
+	age, err := parseAge(rule.Value)
+	if err != nil {
+		return nil, err
+	}
+	return func(item Item, ctx *EvalContext) (bool, error) {
+		t, err := parsePubDate(item.PubDate)
+		if err != nil {
+			return false, err
+		}
+		return ctx.now.Sub(t) <= age, nil
+	}, nil
+}
+
+func compileDelay(rule config.Rule) (Matcher, error) {
+	// Hide items until the configured duration after their publication.
+	visibleAt, err := delayVisibility(rule)
+	if err != nil {
+		return nil, err
+	}
+	return func(item Item, ctx *EvalContext) (bool, error) {
+		visible, err := visibleAt(item)
+		if err != nil {
+			return false, err
+		}
+		return !ctx.now.Before(visible), nil
+	}, nil
+}
+
+// delayVisibility returns when a delay rule lets an item through.
+func delayVisibility(rule config.Rule) (func(Item) (time.Time, error), error) {
+	if err := requireValue(rule); err != nil {
+		return nil, err
+	}
+	delay, err := parseAge(rule.Value)
+	if err != nil {
+		return nil, err
+	}
+	return func(item Item) (time.Time, error) {
+		t, err := parsePubDate(item.PubDate)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return t.Add(delay), nil
+	}, nil
+}
+
+// weekdays maps English day names and abbreviations to weekdays.
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+}
+
+// localPubDate returns the item publication date in the feed timezone.
+func localPubDate(item Item, ctx *EvalContext) (time.Time, error) {
+	t, err := parsePubDate(item.PubDate)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.In(ctx.loc), nil
+}
+
+func compileWeekdayIn(rule config.Rule) (Matcher, error) {
+	// Keep items published on one of the listed days, in the feed timezone.
+	values, err := ruleValues(rule)
+	if err != nil {
+		return nil, err
+	}
+	var days [7]bool
+	for _, v := range values {
+		d, ok := weekdays[strings.ToLower(v)]
+		if !ok {
+			return nil, fmt.Errorf("invalid weekday %q", v)
+		}
+		days[d] = true
+	}
+
+	return func(item Item, ctx *EvalContext) (bool, error) {
+		t, err := localPubDate(item, ctx)
+		if err != nil {
+			return false, err
+		}
+		return days[t.Weekday()], nil
+	}, nil
+}
+
+func compilePublishedHourBetween(rule config.Rule) (Matcher, error) {
+	// Keep items published between the from and to hours (inclusive, 0-23),
+	// in the feed timezone. from > to wraps around midnight (22 to 2).
+	parseHour := func(s string) (int, error) {
+		h, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil || h < 0 || h > 23 {
+			return 0, fmt.Errorf("invalid hour %q (want 0-23)", s)
+		}
+		return h, nil
+	}
+	if strings.TrimSpace(rule.From) == "" || strings.TrimSpace(rule.To) == "" {
+		return nil, errors.New("missing from or to")
+	}
+	from, err := parseHour(rule.From)
+	if err != nil {
+		return nil, err
+	}
+	to, err := parseHour(rule.To)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(item Item, ctx *EvalContext) (bool, error) {
+		t, err := localPubDate(item, ctx)
+		if err != nil {
+			return false, err
+		}
+		h := t.Hour()
+		if from <= to {
+			return h >= from && h <= to, nil
+		}
+		return h >= from || h <= to, nil
+	}, nil
+}
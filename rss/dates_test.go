@@ -0,0 +1,301 @@
+package rss
+
+import (
+	"testing"
+	"time"
+
+	"rss-proxy/config"
+)
+
+func TestParsePubDateVariants(t *testing.T) {
+	want := time.Date(2024, 3, 5, 14, 30, 0, 0, time.UTC)
+
+	cases := []string{
+		"Tue, 05 Mar 2024 14:30:00 +0000",
+		"Tue, 5 Mar 2024 14:30:00 GMT",
+		"Tue, 05 Mar 2024 09:30:00 EST",
+		"Tue,  5 Mar 2024 14:30 +0000",
+		"5 Mar 2024 14:30:00 +0000",
+		"Tue, 05 Mar 24 14:30:00 +0000",
+		"Tuesday, 05 March 2024 15:30:00 +0100",
+		"2024-03-05T14:30:00Z",
+	}
+
+	for _, s := range cases {
+		got, err := parsePubDate(s)
+		if err != nil {
+			t.Fatalf("%q: %v", s, err)
+		}
+		if !got.Equal(want) {
+			t.Fatalf("%q: expected %s, got %s", s, want, got)
+		}
+	}
+
+	if _, err := parsePubDate("yesterday"); err == nil {
+		t.Fatal("expected an error for an invalid date")
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	cases := map[string]time.Duration{
+		"90d":   90 * 24 * time.Hour,
+		"2w":    14 * 24 * time.Hour,
+		"36h":   36 * time.Hour,
+		"1d12h": 36 * time.Hour,
+		"1h30m": 90 * time.Minute,
+	}
+
+	for s, want := range cases {
+		got, err := parseAge(s)
+		if err != nil {
+			t.Fatalf("%q: %v", s, err)
+		}
+		if got != want {
+			t.Fatalf("%q: expected %s, got %s", s, want, got)
+		}
+	}
+
+	for _, s := range []string{"", "d", "ninety days", "-5d"} {
+		if _, err := parseAge(s); err == nil {
+			t.Fatalf("%q: expected an error", s)
+		}
+	}
+}
+
+func TestPublicationDateRules(t *testing.T) {
+	feed := RSS{
+		Channel: Channel{
+			Items: []Item{
+				{Title: "Recent", PubDate: "Mon, 02 Dec 2024 06:00:00 +0000"},
+				{Title: "Old", PubDate: "Fri, 01 Mar 2024 06:00:00 +0000"},
+				{Title: "Ancient", PubDate: "Sun, 01 Jan 2023 06:00:00 +0000"},
+				{Title: "Undated"},
+			},
+		},
+	}
+
+	rs, err := CompileFeed(config.Feed{
+		ID:          "news",
+		OnRuleError: OnRuleErrorDrop,
+		Rules: []config.Rule{
+			{Type: "published_after", Value: "2024-01-01"},
+			{Type: "max_age", Value: "90d"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs.now = func() time.Time { return time.Date(2024, 12, 13, 12, 0, 0, 0, time.UTC) }
+
+	out, err := rs.Apply(feed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Channel.Items) != 1 || out.Channel.Items[0].Title != "Recent" {
+		t.Fatalf("expected only Recent, got %+v", out.Channel.Items)
+	}
+
+	before := ApplyRules(feed, []config.Rule{{Type: "published_before", Value: "2024-01-01"}})
+	// Undated is kept: the default on_rule_error policy is keep.
+	if len(before.Channel.Items) != 2 || before.Channel.Items[0].Title != "Ancient" {
+		t.Fatalf("expected Ancient and Undated, got %+v", before.Channel.Items)
+	}
+}
+
+func TestCalendarRulesUseFeedTimezone(t *testing.T) {
+	feed := RSS{
+		Channel: Channel{
+			Items: []Item{
+				// Saturday 00:30 in Paris, still Friday in UTC.
+				{Title: "Weekend edition", PubDate: "Fri, 06 Dec 2024 23:30:00 +0000"},
+				{Title: "Flash", PubDate: "Mon, 09 Dec 2024 07:00:00 +0100"},
+				{Title: "Late flash", PubDate: "Mon, 09 Dec 2024 22:00:00 EST"},
+			},
+		},
+	}
+
+	rs, err := CompileFeed(config.Feed{
+		ID:       "news",
+		Timezone: "Europe/Paris",
+		Rules:    []config.Rule{{Type: "weekday_in", Values: []string{"sat", "Sunday"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := rs.Apply(feed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Channel.Items) != 1 || out.Channel.Items[0].Title != "Weekend edition" {
+		t.Fatalf("expected only Weekend edition, got %+v", out.Channel.Items)
+	}
+
+	// Late flash is published at 04:00 on Tuesday in Paris: 22 to 5 wraps midnight.
+	rs, err = CompileFeed(config.Feed{
+		ID:       "news",
+		Timezone: "Europe/Paris",
+		Rules:    []config.Rule{{Type: "published_hour_between", From: "22", To: "5"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err = rs.Apply(feed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Channel.Items) != 2 || out.Channel.Items[1].Title != "Late flash" {
+		t.Fatalf("expected Weekend edition and Late flash, got %+v", out.Channel.Items)
+	}
+
+	for _, f := range []config.Feed{
+		{ID: "news", Timezone: "Mars/Olympus"},
+		{ID: "news", Rules: []config.Rule{{Type: "weekday_in", Value: "samedi"}}},
+		{ID: "news", Rules: []config.Rule{{Type: "published_hour_between", From: "6", To: "24"}}},
+	} {
+		if _, err := CompileFeed(f); err == nil {
+			t.Fatalf("%+v: expected an error", f)
+		}
+	}
+}
+
+func TestDelayRule(t *testing.T) {
+	feed := RSS{
+		Channel: Channel{
+			Items: []Item{
+				{Title: "Fresh", PubDate: "Fri, 13 Dec 2024 10:00:00 +0000"},
+				{Title: "Fresher", PubDate: "Fri, 13 Dec 2024 11:00:00 +0000"},
+				{Title: "Settled", PubDate: "Fri, 13 Dec 2024 05:00:00 +0000"},
+			},
+		},
+	}
+
+	rs, err := CompileRules("news", []config.Rule{{Type: "delay", Value: "6h"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 12, 13, 12, 0, 0, 0, time.UTC)
+	rs.now = func() time.Time { return now }
+
+	out, ctx, err := rs.apply(feed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Channel.Items) != 1 || out.Channel.Items[0].Title != "Settled" {
+		t.Fatalf("expected only Settled, got %+v", out.Channel.Items)
+	}
+	if want := time.Date(2024, 12, 13, 16, 0, 0, 0, time.UTC); !ctx.nextVisible.Equal(want) {
+		t.Fatalf("next visibility: got %v, want %v", ctx.nextVisible, want)
+	}
+
+	now = now.Add(4 * time.Hour)
+	out, _ = rs.Apply(feed)
+	if len(out.Channel.Items) != 2 {
+		t.Fatalf("expected Fresh to be visible at 16:00, got %+v", out.Channel.Items)
+	}
+}
+
+func TestDelayNextVisibleOnlyForShownItems(t *testing.T) {
+	feed := RSS{
+		Channel: Channel{
+			Items: []Item{
+				{Title: "Settled", GUID: GUID{Value: "ep-1"}, PubDate: "Fri, 13 Dec 2024 05:00:00 +0000"},
+				{Title: "TRAILER", GUID: GUID{Value: "ep-1"}, PubDate: "Fri, 13 Dec 2024 11:00:00 +0000"},
+			},
+		},
+	}
+	delay := config.Rule{Type: "delay", Value: "6h"}
+
+	for name, rules := range map[string][]config.Rule{
+		// The trailer is hidden for good by title_excludes.
+		"excluded later": {delay, {Type: "title_excludes", Value: "TRAILER"}},
+		// Fresh items are the ones kept.
+		"not": {{Not: &delay}},
+		// The group decides, whatever its members.
+		"any": {{Any: []config.Rule{delay, {Type: "title_contains", Value: "Settled"}}}},
+		// The trailer copy is dropped once visible.
+		"dedupe": {delay, {Type: "dedupe", Key: "guid"}},
+	} {
+		rs, err := CompileRules("news", rules)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rs.now = func() time.Time { return time.Date(2024, 12, 13, 12, 0, 0, 0, time.UTC) }
+
+		_, ctx, err := rs.apply(feed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ctx.nextVisible.IsZero() {
+			t.Fatalf("%s: unexpected next visibility %v", name, ctx.nextVisible)
+		}
+	}
+	// An episode the other rules keep still counts, up to the longest delay.
+	rs, err := CompileRules("news", []config.Rule{
+		{Type: "delay", Value: "1h"},
+		delay,
+		{Type: "title_excludes", Value: "TRAILER"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs.now = func() time.Time { return time.Date(2024, 12, 13, 11, 30, 0, 0, time.UTC) }
+	feed.Channel.Items[1].Title = "Fresh"
+	_, ctx, err := rs.apply(feed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2024, 12, 13, 17, 0, 0, 0, time.UTC); !ctx.nextVisible.Equal(want) {
+		t.Fatalf("next visibility: got %v, want %v", ctx.nextVisible, want)
+	}
+}
+
+func TestDelayNextVisibleFirstMatch(t *testing.T) {
+	fresh := Item{Title: "Fresh", PubDate: "Fri, 13 Dec 2024 11:00:00 +0000"}
+	trailer := Item{Title: "TRAILER", PubDate: "Fri, 13 Dec 2024 10:00:00 +0000"}
+	delay := config.Rule{Type: "delay", Value: "6h"}
+	noTrailers := config.Rule{Type: "title_contains", Value: "TRAILER", Action: ActionDrop}
+
+	for _, tt := range []struct {
+		name          string
+		rules         []config.Rule
+		defaultAction string
+		items         []Item
+		want          time.Time
+	}{
+		{
+			name:  "not delay, drop",
+			rules: []config.Rule{{Not: &delay, Action: ActionDrop}, noTrailers},
+			items: []Item{trailer, fresh},
+			want:  time.Date(2024, 12, 13, 17, 0, 0, 0, time.UTC),
+		},
+		{
+			name:          "delay, keep",
+			rules:         []config.Rule{noTrailers, {Type: "delay", Value: "6h", Action: ActionKeep}},
+			defaultAction: ActionDrop,
+			items:         []Item{trailer, fresh},
+			want:          time.Date(2024, 12, 13, 17, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "dropped anyway",
+			rules: []config.Rule{noTrailers, {Not: &delay, Action: ActionDrop}},
+			items: []Item{trailer},
+		},
+	} {
+		rs, err := CompileFeed(config.Feed{ID: "news", Mode: ModeFirstMatch, DefaultAction: tt.defaultAction, Rules: tt.rules})
+		if err != nil {
+			t.Fatal(err)
+		}
+		rs.now = func() time.Time { return time.Date(2024, 12, 13, 12, 0, 0, 0, time.UTC) }
+
+		out, ctx, err := rs.apply(RSS{Channel: Channel{Items: tt.items}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(out.Channel.Items) != 0 {
+			t.Fatalf("%s: expected every item hidden, got %+v", tt.name, out.Channel.Items)
+		}
+		if !ctx.nextVisible.Equal(tt.want) {
+			t.Fatalf("%s: next visibility: got %v, want %v", tt.name, ctx.nextVisible, tt.want)
+		}
+	}
+}
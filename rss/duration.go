@@ -0,0 +1,161 @@
+package rss
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"rss-proxy/config"
+)
+
+var (
+	reDigits  = regexp.MustCompile(`^\d+$`)
+	reSeconds = regexp.MustCompile(`^\d+(\.\d+)?$`)
+)
+
+// parseITunesDurationToSeconds parses common iTunes duration formats.
+//
+// iTunes duration can be either:
+//   - integer or decimal seconds ("1234", "1234.5")
+//   - "MM:SS"
+//   - "HH:MM:SS" (or "H:MM:SS")
+//
+// The seconds part may carry a decimal fraction ("01:02:03.5"); fractions are
+// rounded to the nearest second.
+//
+// Returns (seconds, true) on success, (0, false) otherwise.
+func parseITunesDurationToSeconds(s string) (int, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, false
+	}
+
+	parts := strings.Split(s, ":")
+	if len(parts) > 3 {
+		return 0, false
+	}
+
+	toInt := func(p string) (int, bool) {
+		p = strings.TrimSpace(p)
+		if !reDigits.MatchString(p) {
+			return 0, false
+		}
+		n, err := strconv.Atoi(p)
+		return n, err == nil
+	}
+
+	// The last part holds (possibly decimal) seconds. ParseFloat alone would
+	// accept signs and exponents ("1e3").
+	last := strings.TrimSpace(parts[len(parts)-1])
+	if !reSeconds.MatchString(last) {
+		return 0, false
+	}
+	secs, err := strconv.ParseFloat(last, 64)
+	if err != nil || math.IsInf(secs, 0) {
+		return 0, false
+	}
+	total := int(math.Round(secs))
+
+	multiplier := 60
+	for i := len(parts) - 2; i >= 0; i-- {
+		n, ok := toInt(parts[i])
+		if !ok {
+			return 0, false
+		}
+		total += n * multiplier
+		multiplier *= 60
+	}
+	return total, true
+}
+
+// parseDurationValue parses a duration from the configuration, in seconds.
+//
+// Besides the iTunes formats ("3600", "01:00:00"), it accepts durations with
+// units such as "45m", "1h30m" or "90s".
+func parseDurationValue(s string) (int, error) {
+	if n, ok := parseITunesDurationToSeconds(s); ok {
+		return n, nil
+	}
+	d, err := parseAge(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return int(d.Seconds()), nil
+}
+
+// itemDuration returns the item <itunes:duration> in seconds.
+func itemDuration(item Item) (int, error) {
+	if strings.TrimSpace(item.Duration) == "" {
+		// Some feeds don't provide duration.
+		return 0, errors.New("no duration provided")
+	}
+	n, ok := parseITunesDurationToSeconds(item.Duration)
+	if !ok {
+		return 0, fmt.Errorf("can't parse duration %q", item.Duration)
+	}
+	return n, nil
+}
+
+// compileLengthRange builds a matcher keeping items whose duration is within
+// [minSec, maxSec]; a negative bound is open.
+func compileLengthRange(minSec, maxSec int) Matcher {
+	return func(item Item, _ *EvalContext) (bool, error) {
+		n, err := itemDuration(item)
+		if err != nil {
+			return false, err
+		}
+		if minSec >= 0 && n < minSec {
+			return false, nil
+		}
+		if maxSec >= 0 && n > maxSec {
+			return false, nil
+		}
+		return true, nil
+	}
+}
+
+func compileLengthMax(rule config.Rule) (Matcher, error) {
+	// Keep items whose iTunes duration is <= the configured max.
+	if err := requireValue(rule); err != nil {
+		return nil, err
+	}
+	maxSec, err := parseDurationValue(rule.Value)
+	if err != nil {
+		return nil, err
+	}
+	return compileLengthRange(-1, maxSec), nil
+}
+
+func compileLengthMin(rule config.Rule) (Matcher, error) {
+	// Keep items whose iTunes duration is >= the configured min.
+	if err := requireValue(rule); err != nil {
+		return nil, err
+	}
+	minSec, err := parseDurationValue(rule.Value)
+	if err != nil {
+		return nil, err
+	}
+	return compileLengthRange(minSec, -1), nil
+}
+
+func compileLengthBetween(rule config.Rule) (Matcher, error) {
+	// Keep items whose iTunes duration is within [from, to].
+	if strings.TrimSpace(rule.From) == "" || strings.TrimSpace(rule.To) == "" {
+		return nil, errors.New("missing from or to")
+	}
+	minSec, err := parseDurationValue(rule.From)
+	if err != nil {
+		return nil, err
+	}
+	maxSec, err := parseDurationValue(rule.To)
+	if err != nil {
+		return nil, err
+	}
+	if minSec > maxSec {
+		return nil, fmt.Errorf("from %q is greater than to %q", rule.From, rule.To)
+	}
+	return compileLengthRange(minSec, maxSec), nil
+}
@@ -0,0 +1,83 @@
+package rss
+
+import (
+	"testing"
+
+	"rss-proxy/config"
+)
+
+func TestParseITunesDurationToSeconds(t *testing.T) {
+	cases := map[string]int{
+		"1234":       1234,
+		"1234.5":     1235,
+		"59:59":      3599,
+		"1:02:03":    3723,
+		"01:02:03.4": 3723,
+	}
+	for in, want := range cases {
+		got, ok := parseITunesDurationToSeconds(in)
+		if !ok || got != want {
+			t.Fatalf("%q: expected %d, got %d (ok=%v)", in, want, got, ok)
+		}
+	}
+
+	for _, in := range []string{"", "abc", "1:2:3:4", "-5", "10:-1", "1e3", "+5", "+1:00", "1:1e1", ".5", "NaN", "Inf"} {
+		if _, ok := parseITunesDurationToSeconds(in); ok {
+			t.Fatalf("%q: expected failure", in)
+		}
+	}
+}
+
+func TestParseDurationValue(t *testing.T) {
+	cases := map[string]int{
+		"3600":     3600,
+		"01:00:00": 3600,
+		"45m":      2700,
+		"1h30m":    5400,
+		"90s":      90,
+	}
+	for in, want := range cases {
+		got, err := parseDurationValue(in)
+		if err != nil || got != want {
+			t.Fatalf("%q: expected %d, got %d (%v)", in, want, got, err)
+		}
+	}
+
+	for _, in := range []string{"1e3", "+5", "-5m"} {
+		if got, err := parseDurationValue(in); err == nil {
+			t.Fatalf("%q: expected failure, got %d", in, got)
+		}
+	}
+}
+
+func TestLengthMinAndBetweenRules(t *testing.T) {
+	feed := RSS{
+		Channel: Channel{
+			Items: []Item{
+				{Title: "Teaser", Duration: "120"},
+				{Title: "Episode", Duration: "45:00"},
+				{Title: "Special", Duration: "7200.5"},
+			},
+		},
+	}
+
+	out := ApplyRules(feed, []config.Rule{{Type: "length_min", Value: "5m"}})
+	if len(out.Channel.Items) != 2 || out.Channel.Items[0].Title != "Episode" {
+		t.Fatalf("expected Episode and Special, got %+v", out.Channel.Items)
+	}
+
+	out = ApplyRules(feed, []config.Rule{{Type: "length_between", From: "5m", To: "1h30m"}})
+	if len(out.Channel.Items) != 1 || out.Channel.Items[0].Title != "Episode" {
+		t.Fatalf("expected only Episode, got %+v", out.Channel.Items)
+	}
+
+	for _, rule := range []config.Rule{
+		{Type: "length_min", Value: "five minutes"},
+		{Type: "length_between", From: "5m"},
+		{Type: "length_between", From: "1h", To: "5m"},
+	} {
+		if _, err := CompileRules("legend", []config.Rule{rule}); err == nil {
+			t.Fatalf("%+v: expected an error", rule)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package rss
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"strconv"
+	"strings"
+
+	"rss-proxy/config"
+)
+
+// hasEnclosure reports whether the item has an enclosure with a media URL.
+func hasEnclosure(item Item) bool {
+	return item.Enclosure != nil && strings.TrimSpace(item.Enclosure.URL) != ""
+}
+
+// enclosureSize returns the enclosure length in bytes.
+// Missing, zero or unparseable lengths are reported as errors.
+func enclosureSize(item Item) (int, error) {
+	if !hasEnclosure(item) {
+		return 0, errors.New("no enclosure")
+	}
+	s := strings.TrimSpace(item.Enclosure.Length)
+	n, err := strconv.Atoi(s)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("can't parse enclosure length %q", s)
+	}
+	return n, nil
+}
+
+func compileEnclosureType(rule config.Rule) (Matcher, error) {
+	// Keep items whose enclosure media type matches one of the values.
+	// Values are full types ("audio/mpeg") or wildcards ("audio/*").
+	values, err := ruleValues(rule)
+	if err != nil {
+		return nil, err
+	}
+	patterns := make([]string, len(values))
+	for i, v := range values {
+		v = strings.ToLower(v)
+		if !strings.Contains(v, "/") {
+			return nil, fmt.Errorf("invalid media type %q", v)
+		}
+		patterns[i] = v
+	}
+
+	return func(item Item, _ *EvalContext) (bool, error) {
+		if !hasEnclosure(item) {
+			return false, nil
+		}
+		t, _, err := mime.ParseMediaType(item.Enclosure.Type)
+		if err != nil {
+			return false, fmt.Errorf("can't parse enclosure type %q", item.Enclosure.Type)
+		}
+		for _, p := range patterns {
+			if prefix, ok := strings.CutSuffix(p, "/*"); ok {
+				if strings.HasPrefix(t, prefix+"/") {
+					return true, nil
+				}
+			} else if t == p {
+				return true, nil
+			}
+		}
+		return false, nil
+	}, nil
+}
+
+func compileEnclosureSizeMin(rule config.Rule) (Matcher, error) {
+	// Keep items whose enclosure is at least `min` bytes.
+	if rule.Min <= 0 {
+		return nil, errors.New("missing min (must be > 0)")
+	}
+	return func(item Item, _ *EvalContext) (bool, error) {
+		n, err := enclosureSize(item)
+		if err != nil {
+			return false, err
+		}
+		return n >= rule.Min, nil
+	}, nil
+}
+
+func compileEnclosureSizeMax(rule config.Rule) (Matcher, error) {
+	// Keep items whose enclosure is at most `max` bytes.
+	if rule.Max <= 0 {
+		return nil, errors.New("missing max (must be > 0)")
+	}
+	return func(item Item, _ *EvalContext) (bool, error) {
+		n, err := enclosureSize(item)
+		if err != nil {
+			return false, err
+		}
+		return n <= rule.Max, nil
+	}, nil
+}
+
+func compileHasEnclosure(config.Rule) (Matcher, error) {
+	// Keep items with a media enclosure; use `not` to keep the others.
+	return func(item Item, _ *EvalContext) (bool, error) {
+		return hasEnclosure(item), nil
+	}, nil
+}
@@ -0,0 +1,62 @@
+package rss
+
+import (
+	"testing"
+
+	"rss-proxy/config"
+)
+
+func TestParseEnclosure(t *testing.T) {
+	feed, err := Parse([]byte(sampleRSS))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := feed.Channel.Items[0].Enclosure
+	if e == nil {
+		t.Fatal("expected enclosure to be parsed")
+	}
+	if e.URL != "https://example.com/keep.mp3" || e.Type != "audio/mpeg" {
+		t.Fatalf("unexpected enclosure: %+v", e)
+	}
+}
+
+func TestEnclosureRules(t *testing.T) {
+	feed := RSS{
+		Channel: Channel{
+			Items: []Item{
+				{Title: "Audio", Enclosure: &Enclosure{URL: "https://e.x/a.mp3", Type: "audio/mpeg", Length: "52428800"}},
+				{Title: "Video", Enclosure: &Enclosure{URL: "https://e.x/a.mp4", Type: "video/mp4", Length: "524288000"}},
+				{Title: "Teaser", Enclosure: &Enclosure{URL: "https://e.x/t.m4a", Type: "Audio/x-m4a; codecs=mp4a", Length: "1024"}},
+				{Title: "Announcement"},
+			},
+		},
+	}
+
+	out := ApplyRules(feed, []config.Rule{
+		{Type: "enclosure_type", Value: "audio/*"},
+	})
+	if len(out.Channel.Items) != 2 || out.Channel.Items[1].Title != "Teaser" {
+		t.Fatalf("expected Audio and Teaser, got %+v", out.Channel.Items)
+	}
+
+	out = ApplyRules(feed, []config.Rule{
+		{Type: "has_enclosure"},
+		{Type: "enclosure_size_min", Min: 1 << 20},
+		{Type: "enclosure_size_max", Max: 100 << 20},
+	})
+	if len(out.Channel.Items) != 1 || out.Channel.Items[0].Title != "Audio" {
+		t.Fatalf("expected only Audio, got %+v", out.Channel.Items)
+	}
+
+	out = ApplyRules(feed, []config.Rule{
+		{Not: &config.Rule{Type: "has_enclosure"}},
+	})
+	if len(out.Channel.Items) != 1 || out.Channel.Items[0].Title != "Announcement" {
+		t.Fatalf("expected only Announcement, got %+v", out.Channel.Items)
+	}
+
+	if _, err := CompileRules("legend", []config.Rule{{Type: "enclosure_type", Value: "audio"}}); err == nil {
+		t.Fatal("expected invalid media type to be rejected")
+	}
+}
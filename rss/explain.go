@@ -0,0 +1,157 @@
+package rss
+
+import (
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"rss-proxy/config"
+)
+
+// Decision explains the fate of one feed item under a RuleSet.
+//
+// It is JSON-friendly so that debug endpoints and command-line tools can
+// print it as is.
+type Decision struct {
+	FeedID string `json:"feed_id"`
+	// Position is the item index in the upstream feed.
+	Position int    `json:"position"`
+	Title    string `json:"title"`
+	GUID     string `json:"guid,omitempty"`
+	Kept     bool   `json:"kept"`
+
+	// RejectedBy is the first rule that dropped the item; nil if kept.
+	RejectedBy *RuleRef `json:"rejected_by,omitempty"`
+	// KeptBy is, in first_match mode, the rule whose keep action kept the
+	// item; nil when the feed default_action did.
+	KeptBy *RuleRef `json:"kept_by,omitempty"`
+	// Error is the rule error that decided the item fate, under the
+	// on_rule_error policy.
+	Error string `json:"error,omitempty"`
+}
+
+// RuleRef identifies a configured rule.
+type RuleRef struct {
+	// Index is the position in the feed rules, once rule sets are expanded.
+	// Path is where the rule is configured, as in error messages: "rules[2]",
+	// or `rule_sets["no-reruns"][1]` for a rule from a rule set.
+	Index int    `json:"index"`
+	Path  string `json:"path"`
+	// Type is the rule type, or all, any, not or expr for group and
+	// expression rules.
+	Type string `json:"type"`
+	// Value is what the rule compares items against: its value(s), its
+	// expression, or its other options in YAML flow style.
+	Value string `json:"value,omitempty"`
+}
+
+// Explain evaluates the rules like Apply and reports a decision for every
+// item, in feed order.
+//
+// Unlike Apply it never fails: under on_rule_error: fail, the item whose
+// rule failed is reported as dropped, with the error.
+func (rs *RuleSet) Explain(feed RSS) []Decision {
+	ctx := rs.newContext()
+	decisions := make([]Decision, len(feed.Channel.Items))
+
+	// Selectors return subsets of their input: items are tracked by position.
+	var kept []Item
+	for i, item := range feed.Channel.Items {
+		d := Decision{
+			FeedID:   rs.feedID,
+			Position: i,
+			Title:    item.Title,
+			GUID:     item.GUID.Value,
+			Kept:     true,
+		}
+		decidedBy, keep, err := rs.matchItem(item, ctx)
+		if decidedBy != nil {
+			ref := newRuleRef(decidedBy.index, decidedBy.path, decidedBy.rule)
+			if keep {
+				d.KeptBy = ref
+			} else {
+				d.RejectedBy = ref
+			}
+		}
+		if err != nil {
+			d.Error = err.Error()
+		}
+		if keep {
+			item.index = i
+			kept = append(kept, item)
+		} else {
+			d.Kept = false
+		}
+		decisions[i] = d
+	}
+
+	for _, sel := range rs.selectors {
+		before := kept
+		kept = sel.fn(kept, ctx)
+
+		remaining := make(map[int]bool, len(kept))
+		for _, item := range kept {
+			remaining[item.index] = true
+		}
+		for _, item := range before {
+			if !remaining[item.index] {
+				decisions[item.index].Kept = false
+				decisions[item.index].KeptBy = nil
+				decisions[item.index].RejectedBy = newRuleRef(sel.index, sel.path, sel.rule)
+			}
+		}
+	}
+
+	return decisions
+}
+
+func newRuleRef(index int, path string, rule config.Rule) *RuleRef {
+	ref := &RuleRef{
+		Index: index,
+		Path:  path,
+		Type:  rule.Type,
+		Value: ruleValue(rule),
+	}
+	switch {
+	case rule.Expr != "":
+		ref.Type = "expr"
+	case rule.All != nil:
+		ref.Type = "all"
+	case rule.Any != nil:
+		ref.Type = "any"
+	case rule.Not != nil:
+		ref.Type = "not"
+	}
+	return ref
+}
+
+// ruleValue summarizes what a rule compares items against.
+func ruleValue(rule config.Rule) string {
+	if rule.Expr != "" {
+		return rule.Expr
+	}
+	if values, err := ruleValues(rule); err == nil {
+		return strings.Join(values, ", ")
+	}
+
+	// Other options (min, count, nested rules, custom rule options...):
+	// the rule mapping without its type, in flow style.
+	node, err := ruleNode(rule)
+	if err != nil || node.Kind != yaml.MappingNode {
+		return ""
+	}
+	opts := yaml.Node{Kind: yaml.MappingNode, Style: yaml.FlowStyle}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value != "type" {
+			opts.Content = append(opts.Content, node.Content[i], node.Content[i+1])
+		}
+	}
+	if len(opts.Content) == 0 {
+		return ""
+	}
+	out, err := yaml.Marshal(&opts)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
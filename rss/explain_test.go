@@ -0,0 +1,140 @@
+package rss
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"rss-proxy/config"
+)
+
+func TestExplain(t *testing.T) {
+	rs, err := CompileFeed(config.Feed{
+		ID:          "legend",
+		OnRuleError: OnRuleErrorDrop,
+		Rules: []config.Rule{
+			{Type: "title_excludes", Value: "REDIFF"},
+			{Any: []config.Rule{
+				{Type: "length_min", Value: "5m"},
+				{Type: "title_contains", Value: "bonus"},
+			}},
+			{Type: "keep_latest", Count: 2},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	feed := RSS{Channel: Channel{Items: []Item{
+		{Title: "Jour 642", GUID: GUID{Value: "642"}, Duration: "10:00", PubDate: "2024-12-14"},
+		{Title: "[REDIFF] Jour 100", Duration: "10:00", PubDate: "2024-12-13"},
+		{Title: "Jour 641", Duration: "10:00", PubDate: "2024-12-12"},
+		{Title: "Jour 640", Duration: "10:00", PubDate: "2024-12-11"},
+		{Title: "Teaser", Duration: "01:00", PubDate: "2024-12-10"},
+		{Title: "No duration", PubDate: "2024-12-09"},
+	}}}
+
+	got := rs.Explain(feed)
+	want := []struct {
+		kept bool
+		ref  string
+		err  bool
+	}{
+		{kept: true},
+		{ref: "rules[0] title_excludes REDIFF"},
+		{kept: true},
+		{ref: "rules[2] keep_latest {count: 2}"},
+		{ref: "rules[1] any {any: [{type: length_min, value: 5m}, {type: title_contains, value: bonus}]}"},
+		{ref: "rules[1] any {any: [{type: length_min, value: 5m}, {type: title_contains, value: bonus}]}", err: true},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d decisions, want %d", len(got), len(want))
+	}
+	for i, d := range got {
+		w := want[i]
+		if d.FeedID != "legend" || d.Position != i || d.Title != feed.Channel.Items[i].Title {
+			t.Fatalf("decision %d: unexpected item fields: %+v", i, d)
+		}
+		if d.Kept != w.kept {
+			t.Fatalf("decision %d (%s): kept = %v, want %v", i, d.Title, d.Kept, w.kept)
+		}
+		var ref string
+		if d.RejectedBy != nil {
+			ref = d.RejectedBy.Path + " " + d.RejectedBy.Type + " " + d.RejectedBy.Value
+		}
+		if ref != w.ref {
+			t.Fatalf("decision %d (%s): rejected by %q, want %q", i, d.Title, ref, w.ref)
+		}
+		if (d.Error != "") != w.err {
+			t.Fatalf("decision %d (%s): unexpected error %q", i, d.Title, d.Error)
+		}
+	}
+
+	// The kept items are the ones Apply keeps.
+	out, err := rs.Apply(feed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Channel.Items) != 2 || out.Channel.Items[1].Title != "Jour 641" {
+		t.Fatalf("Apply disagrees with Explain: %+v", out.Channel.Items)
+	}
+}
+
+func TestDecisionJSON(t *testing.T) {
+	d := MustCompileRules("legend", []config.Rule{
+		{Type: "episode_number_min", Min: 640},
+	}).Explain(RSS{Channel: Channel{Items: []Item{{Title: "Jour 639"}}}})
+
+	data, err := json.Marshal(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[{"feed_id":"legend","position":0,"title":"Jour 639","kept":false,` +
+		`"rejected_by":{"index":0,"path":"rules[0]","type":"episode_number_min","value":"{min: 640}"}}]`
+	if string(data) != want {
+		t.Fatalf("got %s\nwant %s", data, want)
+	}
+}
+
+func TestRuleSetOrigins(t *testing.T) {
+	parse := func(rule string) config.Feed {
+		t.Helper()
+		cfg, err := config.Parse([]byte(`
+rule_sets:
+  no-reruns:
+    - type: title_excludes
+      value: REDIFF
+    - ` + rule + `
+feeds:
+  - id: legend
+    use: [no-reruns]
+    rules:
+      - type: episode_number_min
+        min: 640
+`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cfg.Feeds[0]
+	}
+
+	_, err := CompileFeed(parse(`{type: title_regex, value: "[bad"}`))
+	if err == nil || !strings.Contains(err.Error(), `feed "legend": rule_sets["no-reruns"][1]: title_regex:`) {
+		t.Fatalf("expected error naming the rule set, got %v", err)
+	}
+
+	rs, err := CompileFeed(parse(`{type: title_excludes, value: best of}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := rs.Explain(RSS{Channel: Channel{Items: []Item{
+		{Title: "Best of 2024"},
+		{Title: "Jour 639"},
+	}}})
+	if ref := d[0].RejectedBy; ref == nil || ref.Path != `rule_sets["no-reruns"][1]` || ref.Index != 1 {
+		t.Fatalf("unexpected rule for %q: %+v", d[0].Title, ref)
+	}
+	if ref := d[1].RejectedBy; ref == nil || ref.Path != "rules[0]" || ref.Index != 2 {
+		t.Fatalf("unexpected rule for %q: %+v", d[1].Title, ref)
+	}
+}
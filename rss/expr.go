@@ -0,0 +1,797 @@
+package rss
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"rss-proxy/config"
+)
+
+// A small boolean expression language for rules, e.g.
+//
+//	episode >= 640 && !contains(title, "REDIFF") && duration < 3600
+//
+// Expressions are parsed and type-checked once when rules are compiled, then
+// evaluated against each item. Operators, by increasing precedence:
+//
+//	||
+//	&&
+//	== !=
+//	< <= > >=
+//	+ -
+//	* /
+//	! - (unary)
+//
+// Reading an optional value the item doesn't have (e.g. duration) is a rule
+// error handled by on_rule_error; guard with has_duration & co. when needed.
+
+// exprType is the static type of an expression.
+type exprType int
+
+const (
+	typeBool exprType = iota
+	typeNumber
+	typeString
+	typeList
+)
+
+func (t exprType) String() string {
+	switch t {
+	case typeBool:
+		return "bool"
+	case typeNumber:
+		return "number"
+	case typeString:
+		return "string"
+	default:
+		return "list"
+	}
+}
+
+// exprFunc evaluates a compiled expression node.
+// Values are bool, float64, string or []string according to the node type.
+type exprFunc func(item Item, ctx *EvalContext) (any, error)
+
+// exprNode is a type-checked expression.
+type exprNode struct {
+	typ  exprType
+	eval exprFunc
+	// literal holds the value of string literals, for arguments that must be
+	// known at compile time (regexes).
+	literal *string
+}
+
+// exprVar describes a variable exposed to expressions.
+type exprVar struct {
+	typ exprType
+	get exprFunc
+}
+
+// exprVars are the item fields exposed to expressions.
+var exprVars = map[string]exprVar{
+	"title": {typeString, func(item Item, _ *EvalContext) (any, error) {
+		return item.Title, nil
+	}},
+	"description": {typeString, func(item Item, _ *EvalContext) (any, error) {
+		return itemDescription(item), nil
+	}},
+	"author": {typeString, func(item Item, _ *EvalContext) (any, error) {
+		return itemAuthors(item), nil
+	}},
+	"guid": {typeString, func(item Item, _ *EvalContext) (any, error) {
+		return itemGUID(item), nil
+	}},
+	"link": {typeString, func(item Item, _ *EvalContext) (any, error) {
+		return itemLink(item), nil
+	}},
+	"episode_type": {typeString, func(item Item, _ *EvalContext) (any, error) {
+		return episodeType(item), nil
+	}},
+	"categories": {typeList, func(item Item, _ *EvalContext) (any, error) {
+		return item.Categories, nil
+	}},
+	"keywords": {typeList, func(item Item, _ *EvalContext) (any, error) {
+		return itemKeywords(item), nil
+	}},
+	"enclosure_type": {typeString, func(item Item, _ *EvalContext) (any, error) {
+		if !hasEnclosure(item) {
+			return "", nil
+		}
+		return strings.ToLower(strings.TrimSpace(item.Enclosure.Type)), nil
+	}},
+	"enclosure_url": {typeString, func(item Item, _ *EvalContext) (any, error) {
+		if !hasEnclosure(item) {
+			return "", nil
+		}
+		return strings.TrimSpace(item.Enclosure.URL), nil
+	}},
+	"enclosure_size": {typeNumber, func(item Item, _ *EvalContext) (any, error) {
+		n, err := enclosureSize(item)
+		return float64(n), err
+	}},
+	"episode": {typeNumber, func(item Item, ctx *EvalContext) (any, error) {
+		n, ok := episodeNumber(item, ctx)
+		if !ok {
+			return 0.0, errors.New("no episode number")
+		}
+		return float64(n), nil
+	}},
+	"season": {typeNumber, func(item Item, _ *EvalContext) (any, error) {
+		n, err := season(item)
+		return float64(n), err
+	}},
+	"duration": {typeNumber, func(item Item, _ *EvalContext) (any, error) {
+		n, err := itemDuration(item)
+		return float64(n), err
+	}},
+	// age is the number of seconds since publication.
+	"age": {typeNumber, func(item Item, ctx *EvalContext) (any, error) {
+		t, err := parsePubDate(item.PubDate)
+		if err != nil {
+			return 0.0, err
+		}
+		return ctx.now.Sub(t).Seconds(), nil
+	}},
+	// weekday ("mon".."sun") and hour (0-23) of publication, in the feed timezone.
+	"weekday": {typeString, func(item Item, ctx *EvalContext) (any, error) {
+		t, err := localPubDate(item, ctx)
+		if err != nil {
+			return "", err
+		}
+		return strings.ToLower(t.Weekday().String()[:3]), nil
+	}},
+	"hour": {typeNumber, func(item Item, ctx *EvalContext) (any, error) {
+		t, err := localPubDate(item, ctx)
+		if err != nil {
+			return 0.0, err
+		}
+		return float64(t.Hour()), nil
+	}},
+	"has_episode": {typeBool, func(item Item, ctx *EvalContext) (any, error) {
+		_, ok := episodeNumber(item, ctx)
+		return ok, nil
+	}},
+	"has_season": {typeBool, func(item Item, _ *EvalContext) (any, error) {
+		_, err := season(item)
+		return err == nil, nil
+	}},
+	"has_duration": {typeBool, func(item Item, _ *EvalContext) (any, error) {
+		_, err := itemDuration(item)
+		return err == nil, nil
+	}},
+	"has_pub_date": {typeBool, func(item Item, _ *EvalContext) (any, error) {
+		_, err := parsePubDate(item.PubDate)
+		return err == nil, nil
+	}},
+	"has_enclosure": {typeBool, func(item Item, _ *EvalContext) (any, error) {
+		return hasEnclosure(item), nil
+	}},
+}
+
+// exprBuiltin is a function exposed to expressions.
+type exprBuiltin struct {
+	args []exprType
+	ret  exprType
+	// compile builds the call from its type-checked arguments.
+	compile func(args []exprNode, opts exprOptions) (exprFunc, error)
+}
+
+// exprOptions are the text options of an expr rule: text for substring and
+// list lookups (case-insensitive by default), regex for matches()
+// (case-sensitive by default), like the corresponding rule types.
+type exprOptions struct {
+	text  textOptions
+	regex textOptions
+}
+
+func compileExprOptions(rule config.Rule) (exprOptions, error) {
+	text, err := compileTextOptions(rule, false)
+	if err != nil {
+		return exprOptions{}, err
+	}
+	regex, err := compileTextOptions(rule, true)
+	if err != nil {
+		return exprOptions{}, err
+	}
+	return exprOptions{text: text, regex: regex}, nil
+}
+
+// stringPredicate builds a (string, string) -> bool builtin comparing
+// with the rule text options, like the title_* rules.
+func stringPredicate(fn func(s, sub string) bool) exprBuiltin {
+	return exprBuiltin{
+		args: []exprType{typeString, typeString},
+		ret:  typeBool,
+		compile: func(args []exprNode, opts exprOptions) (exprFunc, error) {
+			return func(item Item, ctx *EvalContext) (any, error) {
+				vals, err := evalArgs(args, item, ctx)
+				if err != nil {
+					return nil, err
+				}
+				return fn(opts.text.prepare(vals[0].(string)), opts.text.prepare(vals[1].(string))), nil
+			}, nil
+		},
+	}
+}
+
+// stringFunc builds a string -> string builtin.
+func stringFunc(fn func(string) string) exprBuiltin {
+	return exprBuiltin{
+		args: []exprType{typeString},
+		ret:  typeString,
+		compile: func(args []exprNode, _ exprOptions) (exprFunc, error) {
+			return func(item Item, ctx *EvalContext) (any, error) {
+				v, err := args[0].eval(item, ctx)
+				if err != nil {
+					return nil, err
+				}
+				return fn(v.(string)), nil
+			}, nil
+		},
+	}
+}
+
+// exprBuiltins are the functions exposed to expressions.
+var exprBuiltins = map[string]exprBuiltin{
+	"contains":    stringPredicate(strings.Contains),
+	"starts_with": stringPredicate(strings.HasPrefix),
+	"ends_with":   stringPredicate(strings.HasSuffix),
+	"lower":       stringFunc(strings.ToLower),
+	"upper":       stringFunc(strings.ToUpper),
+	"trim":        stringFunc(strings.TrimSpace),
+
+	// matches(s, "regex"): the regex must be a string literal.
+	"matches": {
+		args: []exprType{typeString, typeString},
+		ret:  typeBool,
+		compile: func(args []exprNode, opts exprOptions) (exprFunc, error) {
+			if args[1].literal == nil {
+				return nil, errors.New("matches: the regex must be a string literal")
+			}
+			re, err := opts.regex.compileRegexp(*args[1].literal)
+			if err != nil {
+				return nil, fmt.Errorf("matches: %w", err)
+			}
+			return func(item Item, ctx *EvalContext) (any, error) {
+				v, err := args[0].eval(item, ctx)
+				if err != nil {
+					return nil, err
+				}
+				return re.MatchString(opts.regex.fold(v.(string))), nil
+			}, nil
+		},
+	},
+
+	// has(list, s): whether the list holds s, ignoring case by default.
+	"has": {
+		args: []exprType{typeList, typeString},
+		ret:  typeBool,
+		compile: func(args []exprNode, opts exprOptions) (exprFunc, error) {
+			return func(item Item, ctx *EvalContext) (any, error) {
+				vals, err := evalArgs(args, item, ctx)
+				if err != nil {
+					return nil, err
+				}
+				want := opts.text.prepare(strings.TrimSpace(vals[1].(string)))
+				for _, v := range vals[0].([]string) {
+					if opts.text.prepare(strings.TrimSpace(v)) == want {
+						return true, nil
+					}
+				}
+				return false, nil
+			}, nil
+		},
+	},
+
+	// len(s): the number of characters of a string.
+	"len": {
+		args: []exprType{typeString},
+		ret:  typeNumber,
+		compile: func(args []exprNode, _ exprOptions) (exprFunc, error) {
+			return func(item Item, ctx *EvalContext) (any, error) {
+				v, err := args[0].eval(item, ctx)
+				if err != nil {
+					return nil, err
+				}
+				return float64(utf8.RuneCountInString(v.(string))), nil
+			}, nil
+		},
+	},
+
+	// count(list): the number of entries of a list.
+	"count": {
+		args: []exprType{typeList},
+		ret:  typeNumber,
+		compile: func(args []exprNode, _ exprOptions) (exprFunc, error) {
+			return func(item Item, ctx *EvalContext) (any, error) {
+				v, err := args[0].eval(item, ctx)
+				if err != nil {
+					return nil, err
+				}
+				return float64(len(v.([]string))), nil
+			}, nil
+		},
+	},
+}
+
+func evalArgs(args []exprNode, item Item, ctx *EvalContext) ([]any, error) {
+	vals := make([]any, len(args))
+	for i, a := range args {
+		v, err := a.eval(item, ctx)
+		if err != nil {
+			return nil, err
+		}
+		vals[i] = v
+	}
+	return vals, nil
+}
+
+// compileExpr parses and type-checks a boolean expression.
+func compileExpr(src string, opts exprOptions) (exprFunc, error) {
+	toks, err := lexExpr(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{toks: toks, opts: opts}
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, p.errorf(t, "unexpected %q", t.text)
+	}
+	if n.typ != typeBool {
+		return nil, fmt.Errorf("expression is a %s, want bool", n.typ)
+	}
+	return n.eval, nil
+}
+
+func compileExprRule(rule config.Rule) (Matcher, error) {
+	opts, err := compileExprOptions(rule)
+	if err != nil {
+		return nil, err
+	}
+	eval, err := compileExpr(rule.Expr, opts)
+	if err != nil {
+		return nil, err
+	}
+	return func(item Item, ctx *EvalContext) (bool, error) {
+		v, err := eval(item, ctx)
+		if err != nil {
+			return false, err
+		}
+		return v.(bool), nil
+	}, nil
+}
+
+// Lexer.
+
+type tokKind int
+
+const (
+	tokEOF tokKind = iota
+	tokNumber
+	tokString
+	tokIdent
+	tokOp
+)
+
+type exprToken struct {
+	kind tokKind
+	text string // operator or identifier text, or decoded string literal
+	num  float64
+	pos  int // byte offset in the source, for error messages
+}
+
+// exprOps lists operators, longest first.
+var exprOps = []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "+", "-", "*", "/", "(", ")", ","}
+
+func lexExpr(src string) ([]exprToken, error) {
+	var toks []exprToken
+	i := 0
+	for i < len(src) {
+		r, size := utf8.DecodeRuneInString(src[i:])
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+
+		case r >= '0' && r <= '9' || r == '.':
+			j := i
+			for j < len(src) && (src[j] >= '0' && src[j] <= '9' || src[j] == '.') {
+				j++
+			}
+			n, err := strconv.ParseFloat(src[i:j], 64)
+			if err != nil {
+				return nil, fmt.Errorf("col %d: invalid number %q", i+1, src[i:j])
+			}
+			toks = append(toks, exprToken{kind: tokNumber, text: src[i:j], num: n, pos: i})
+			i = j
+
+		case r == '"' || r == '\'':
+			s, n, err := lexString(src[i:])
+			if err != nil {
+				return nil, fmt.Errorf("col %d: %w", i+1, err)
+			}
+			toks = append(toks, exprToken{kind: tokString, text: s, pos: i})
+			i += n
+
+		case r == '_' || unicode.IsLetter(r):
+			j := i
+			for j < len(src) {
+				r, size := utf8.DecodeRuneInString(src[j:])
+				if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+					break
+				}
+				j += size
+			}
+			toks = append(toks, exprToken{kind: tokIdent, text: src[i:j], pos: i})
+			i = j
+
+		default:
+			op := ""
+			for _, o := range exprOps {
+				if strings.HasPrefix(src[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("col %d: unexpected character %q", i+1, r)
+			}
+			toks = append(toks, exprToken{kind: tokOp, text: op, pos: i})
+			i += len(op)
+		}
+	}
+	return append(toks, exprToken{kind: tokEOF, pos: len(src)}), nil
+}
+
+// lexString decodes a quoted string literal at the start of s and returns it
+// with the number of bytes consumed. Backslash escapes the next character.
+func lexString(s string) (string, int, error) {
+	quote := s[0]
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 == len(s) {
+				return "", 0, errors.New("unterminated string")
+			}
+			i++
+			b.WriteByte(s[i])
+		case quote:
+			return b.String(), i + 1, nil
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return "", 0, errors.New("unterminated string")
+}
+
+// Parser: recursive descent, type-checking as it goes.
+
+type exprParser struct {
+	toks []exprToken
+	pos  int
+	opts exprOptions
+}
+
+func (p *exprParser) peek() exprToken { return p.toks[p.pos] }
+
+func (p *exprParser) next() exprToken {
+	t := p.toks[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+// accept consumes the next token if it is one of the operators.
+func (p *exprParser) accept(ops ...string) (string, bool) {
+	t := p.peek()
+	if t.kind != tokOp {
+		return "", false
+	}
+	for _, op := range ops {
+		if t.text == op {
+			p.pos++
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *exprParser) errorf(t exprToken, format string, args ...any) error {
+	return fmt.Errorf("col %d: %s", t.pos+1, fmt.Sprintf(format, args...))
+}
+
+func (p *exprParser) parseOr() (exprNode, error) {
+	return p.parseLogical("||", p.parseAnd)
+}
+
+func (p *exprParser) parseAnd() (exprNode, error) {
+	return p.parseLogical("&&", p.parseEquality)
+}
+
+// parseLogical parses a chain of short-circuit && or || operators.
+func (p *exprParser) parseLogical(op string, operand func() (exprNode, error)) (exprNode, error) {
+	left, err := operand()
+	if err != nil {
+		return exprNode{}, err
+	}
+	for {
+		t := p.peek()
+		if _, ok := p.accept(op); !ok {
+			return left, nil
+		}
+		right, err := operand()
+		if err != nil {
+			return exprNode{}, err
+		}
+		if left.typ != typeBool || right.typ != typeBool {
+			return exprNode{}, p.errorf(t, "%s needs bool operands, got %s and %s", op, left.typ, right.typ)
+		}
+
+		l, r, isOr := left.eval, right.eval, op == "||"
+		left = exprNode{typ: typeBool, eval: func(item Item, ctx *EvalContext) (any, error) {
+			v, err := l(item, ctx)
+			if err != nil {
+				return nil, err
+			}
+			if v.(bool) == isOr {
+				return isOr, nil
+			}
+			return r(item, ctx)
+		}}
+	}
+}
+
+func (p *exprParser) parseEquality() (exprNode, error) {
+	left, err := p.parseComparison()
+	if err != nil {
+		return exprNode{}, err
+	}
+	for {
+		t := p.peek()
+		op, ok := p.accept("==", "!=")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseComparison()
+		if err != nil {
+			return exprNode{}, err
+		}
+		if left.typ != right.typ || left.typ == typeList {
+			return exprNode{}, p.errorf(t, "can't compare %s %s %s", left.typ, op, right.typ)
+		}
+
+		l, r, negate := left.eval, right.eval, op == "!="
+		left = exprNode{typ: typeBool, eval: func(item Item, ctx *EvalContext) (any, error) {
+			lv, err := l(item, ctx)
+			if err != nil {
+				return nil, err
+			}
+			rv, err := r(item, ctx)
+			if err != nil {
+				return nil, err
+			}
+			return (lv == rv) != negate, nil
+		}}
+	}
+}
+
+func (p *exprParser) parseComparison() (exprNode, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return exprNode{}, err
+	}
+	for {
+		t := p.peek()
+		op, ok := p.accept("<", "<=", ">", ">=")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseAdditive()
+		if err != nil {
+			return exprNode{}, err
+		}
+		if left.typ != typeNumber || right.typ != typeNumber {
+			return exprNode{}, p.errorf(t, "%s needs number operands, got %s and %s", op, left.typ, right.typ)
+		}
+
+		cmp := map[string]func(a, b float64) bool{
+			"<":  func(a, b float64) bool { return a < b },
+			"<=": func(a, b float64) bool { return a <= b },
+			">":  func(a, b float64) bool { return a > b },
+			">=": func(a, b float64) bool { return a >= b },
+		}[op]
+		left = numberOp(typeBool, left, right, func(a, b float64) (any, error) { return cmp(a, b), nil })
+	}
+}
+
+func (p *exprParser) parseAdditive() (exprNode, error) {
+	return p.parseArithmetic([]string{"+", "-"}, p.parseMultiplicative)
+}
+
+func (p *exprParser) parseMultiplicative() (exprNode, error) {
+	return p.parseArithmetic([]string{"*", "/"}, p.parseUnary)
+}
+
+func (p *exprParser) parseArithmetic(ops []string, operand func() (exprNode, error)) (exprNode, error) {
+	left, err := operand()
+	if err != nil {
+		return exprNode{}, err
+	}
+	for {
+		t := p.peek()
+		op, ok := p.accept(ops...)
+		if !ok {
+			return left, nil
+		}
+		right, err := operand()
+		if err != nil {
+			return exprNode{}, err
+		}
+		if left.typ != typeNumber || right.typ != typeNumber {
+			return exprNode{}, p.errorf(t, "%s needs number operands, got %s and %s", op, left.typ, right.typ)
+		}
+
+		left = numberOp(typeNumber, left, right, func(a, b float64) (any, error) {
+			switch op {
+			case "+":
+				return a + b, nil
+			case "-":
+				return a - b, nil
+			case "*":
+				return a * b, nil
+			default:
+				if b == 0 {
+					return nil, errors.New("division by zero")
+				}
+				return a / b, nil
+			}
+		})
+	}
+}
+
+// numberOp combines two number nodes.
+func numberOp(typ exprType, left, right exprNode, fn func(a, b float64) (any, error)) exprNode {
+	l, r := left.eval, right.eval
+	return exprNode{typ: typ, eval: func(item Item, ctx *EvalContext) (any, error) {
+		lv, err := l(item, ctx)
+		if err != nil {
+			return nil, err
+		}
+		rv, err := r(item, ctx)
+		if err != nil {
+			return nil, err
+		}
+		return fn(lv.(float64), rv.(float64))
+	}}
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	t := p.peek()
+	op, ok := p.accept("!", "-")
+	if !ok {
+		return p.parsePrimary()
+	}
+	operand, err := p.parseUnary()
+	if err != nil {
+		return exprNode{}, err
+	}
+
+	inner := operand.eval
+	if op == "!" {
+		if operand.typ != typeBool {
+			return exprNode{}, p.errorf(t, "! needs a bool operand, got %s", operand.typ)
+		}
+		return exprNode{typ: typeBool, eval: func(item Item, ctx *EvalContext) (any, error) {
+			v, err := inner(item, ctx)
+			if err != nil {
+				return nil, err
+			}
+			return !v.(bool), nil
+		}}, nil
+	}
+
+	if operand.typ != typeNumber {
+		return exprNode{}, p.errorf(t, "- needs a number operand, got %s", operand.typ)
+	}
+	return exprNode{typ: typeNumber, eval: func(item Item, ctx *EvalContext) (any, error) {
+		v, err := inner(item, ctx)
+		if err != nil {
+			return nil, err
+		}
+		return -v.(float64), nil
+	}}, nil
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	t := p.next()
+	switch t.kind {
+	case tokNumber:
+		n := t.num
+		return exprNode{typ: typeNumber, eval: func(Item, *EvalContext) (any, error) { return n, nil }}, nil
+
+	case tokString:
+		s := t.text
+		return exprNode{typ: typeString, literal: &s, eval: func(Item, *EvalContext) (any, error) { return s, nil }}, nil
+
+	case tokIdent:
+		if _, ok := p.accept("("); ok {
+			return p.parseCall(t)
+		}
+		switch t.text {
+		case "true", "false":
+			b := t.text == "true"
+			return exprNode{typ: typeBool, eval: func(Item, *EvalContext) (any, error) { return b, nil }}, nil
+		}
+		v, ok := exprVars[t.text]
+		if !ok {
+			return exprNode{}, p.errorf(t, "unknown identifier %q", t.text)
+		}
+		return exprNode{typ: v.typ, eval: v.get}, nil
+
+	case tokOp:
+		if t.text == "(" {
+			n, err := p.parseOr()
+			if err != nil {
+				return exprNode{}, err
+			}
+			if _, ok := p.accept(")"); !ok {
+				return exprNode{}, p.errorf(p.peek(), "missing )")
+			}
+			return n, nil
+		}
+	}
+
+	if t.kind == tokEOF {
+		return exprNode{}, p.errorf(t, "unexpected end of expression")
+	}
+	return exprNode{}, p.errorf(t, "unexpected %q", t.text)
+}
+
+// parseCall parses the arguments of a call to fn, whose "(" was consumed.
+func (p *exprParser) parseCall(fn exprToken) (exprNode, error) {
+	b, ok := exprBuiltins[fn.text]
+	if !ok {
+		return exprNode{}, p.errorf(fn, "unknown function %q", fn.text)
+	}
+
+	var args []exprNode
+	if _, ok := p.accept(")"); !ok {
+		for {
+			a, err := p.parseOr()
+			if err != nil {
+				return exprNode{}, err
+			}
+			args = append(args, a)
+			if _, ok := p.accept(","); ok {
+				continue
+			}
+			if _, ok := p.accept(")"); ok {
+				break
+			}
+			return exprNode{}, p.errorf(p.peek(), "expected , or ) in call to %s", fn.text)
+		}
+	}
+
+	if len(args) != len(b.args) {
+		return exprNode{}, p.errorf(fn, "%s takes %d arguments, got %d", fn.text, len(b.args), len(args))
+	}
+	for i, a := range args {
+		if a.typ != b.args[i] {
+			return exprNode{}, p.errorf(fn, "%s: argument %d is a %s, want %s", fn.text, i+1, a.typ, b.args[i])
+		}
+	}
+
+	eval, err := b.compile(args, p.opts)
+	if err != nil {
+		return exprNode{}, p.errorf(fn, "%s", err)
+	}
+	return exprNode{typ: b.ret, eval: eval}, nil
+}
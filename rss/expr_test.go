@@ -0,0 +1,115 @@
+package rss
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"rss-proxy/config"
+)
+
+func evalExpr(t *testing.T, src string, item Item) (bool, error) {
+	t.Helper()
+	opts, err := compileExprOptions(config.Rule{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	eval, err := compileExpr(src, opts)
+	if err != nil {
+		t.Fatalf("%q: %v", src, err)
+	}
+	v, err := eval(item, &EvalContext{now: time.Date(2024, 12, 13, 12, 0, 0, 0, time.UTC)})
+	if err != nil {
+		return false, err
+	}
+	return v.(bool), nil
+}
+
+func TestExprEvaluation(t *testing.T) {
+	item := Item{
+		Title:      "Jour 641 – invité X",
+		Episode:    641,
+		Duration:   "45:00",
+		PubDate:    "Fri, 13 Dec 2024 06:00:00 +0000",
+		Categories: []string{"Bible", "Daily"},
+	}
+
+	cases := map[string]bool{
+		`episode >= 640 && !contains(title, "REDIFF") && duration < 3600`: true,
+		`episode >= 640 && contains(title, "rediff")`:                     false,
+		`contains(title, "INVITÉ") || false`:                              true,
+		`matches(title, "^Jour \\d+")`:                                    true,
+		`has(categories, "daily") && count(categories) == 2`:              true,
+		`duration / 60 == 45 && -episode < 0`:                             true,
+		`age < 24 * 3600 && has_pub_date`:                                 true,
+		`episode_type == 'full' && !has_season`:                           true,
+		`(episode - 1) * 2 != 1280 || starts_with(lower(title), "jour")`:  true,
+	}
+	for src, want := range cases {
+		got, err := evalExpr(t, src, item)
+		if err != nil {
+			t.Fatalf("%q: %v", src, err)
+		}
+		if got != want {
+			t.Fatalf("%q: expected %v, got %v", src, want, got)
+		}
+	}
+
+	// Missing data is a rule error, unless short-circuited by a guard.
+	if _, err := evalExpr(t, `season > 1`, item); err == nil {
+		t.Fatal("expected an error for a missing season")
+	}
+	if got, err := evalExpr(t, `has_season && season > 1`, item); err != nil || got {
+		t.Fatalf("expected guarded expression to be false, got %v, %v", got, err)
+	}
+}
+
+func TestExprTypeCheck(t *testing.T) {
+	cases := map[string]string{
+		`episode >= "640"`:           "needs number operands",
+		`tilte == "x"`:               "unknown identifier",
+		`contains(title)`:            "takes 2 arguments",
+		`episode + 1`:                "want bool",
+		`matches(title, lower("x"))`: "string literal",
+		`matches(title, "[")`:        "missing closing",
+		`(episode > 1`:               "missing )",
+		`title == "x" &&`:            "unexpected end",
+		`title == 'unterminated`:     "unterminated string",
+		`categories == categories`:   "can't compare",
+	}
+	opts, err := compileExprOptions(config.Rule{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for src, want := range cases {
+		_, err := compileExpr(src, opts)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("%q: expected error containing %q, got %v", src, want, err)
+		}
+	}
+}
+
+func TestExprRule(t *testing.T) {
+	feed := RSS{
+		Channel: Channel{
+			Items: []Item{
+				{Title: "Jour 639", Episode: 639, Duration: "10:00"},
+				{Title: "Jour 640", Episode: 640, Duration: "10:00"},
+				{Title: "[REDIFF] Jour 641", Episode: 641, Duration: "10:00"},
+				{Title: "Jour 642", Episode: 642, Duration: "01:10:00"},
+			},
+		},
+	}
+
+	out := ApplyRules(feed, []config.Rule{
+		{Expr: `episode >= 640 && !contains(title, "REDIFF") && duration < 3600`},
+	})
+	if len(out.Channel.Items) != 1 || out.Channel.Items[0].Title != "Jour 640" {
+		t.Fatalf("expected only Jour 640, got %+v", out.Channel.Items)
+	}
+
+	_, err := CompileRules("bible", []config.Rule{{Expr: `episode >= "640"`}})
+	if err == nil || !strings.Contains(err.Error(), "rules[0]: expr: col 9") {
+		t.Fatalf("expected a load-time type error, got %v", err)
+	}
+}
@@ -0,0 +1,301 @@
+package rss
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"rss-proxy/config"
+)
+
+// Field is a child element or attribute of an <item>, as seen by field rules.
+type Field struct {
+	// Path lists element names from the item down, e.g. [podcast:transcript].
+	Path []xml.Name
+	// Attr is the attribute name, or the zero Name for the element text.
+	Attr xml.Name
+	// Value is the attribute value or the element text, trimmed.
+	Value string
+}
+
+// knownPrefixes resolves namespace prefixes in field paths. Feeds declare
+// their own prefixes, but podcast namespaces are used with these by convention;
+// other namespaces can be written as {uri}local.
+//
+// Some namespaces are bound to several URIs in the wild (older or misspelled
+// ones): a prefix matches elements in any of them.
+var knownPrefixes = map[string][]string{
+	"itunes": {
+		"http://www.itunes.com/dtds/podcast-1.0.dtd",
+		"https://www.itunes.com/dtds/podcast-1.0.dtd",
+	},
+	"podcast": {
+		"https://podcastindex.org/namespace/1.0",
+		"https://github.com/Podcastindex-org/podcast-namespace/blob/main/docs/1.0.md",
+	},
+	"dc":      {"http://purl.org/dc/elements/1.1/"},
+	"content": {"http://purl.org/rss/1.0/modules/content/"},
+	"media": {
+		"http://search.yahoo.com/mrss/",
+		"http://search.yahoo.com/mrss",
+	},
+	"atom":       {"http://www.w3.org/2005/Atom"},
+	"googleplay": {"http://www.google.com/schemas/play-podcasts/1.0"},
+	"spotify":    {"http://www.spotify.com/ns/rss"},
+	"psc":        {"http://podlove.org/simple-chapters"},
+}
+
+// usesFields reports whether rule, or a rule nested in it, may read
+// Item.Fields: field rules, and rule types registered by library users.
+func usesFields(rule config.Rule) bool {
+	if rule.Type == "field_regex" || (rule.Type != "" && builtinCompilers[rule.Type] == nil) {
+		return true
+	}
+	for _, r := range rule.All {
+		if usesFields(r) {
+			return true
+		}
+	}
+	for _, r := range rule.Any {
+		if usesFields(r) {
+			return true
+		}
+	}
+	return rule.Not != nil && usesFields(*rule.Not)
+}
+
+// setItemFields sets the Fields of the feed items from a second pass over
+// the document.
+func setItemFields(feed *RSS, data []byte) error {
+	fields, err := parseItemFields(data)
+	if err != nil {
+		return err
+	}
+	if len(fields) != len(feed.Channel.Items) {
+		return fmt.Errorf("found fields for %d items, want %d", len(fields), len(feed.Channel.Items))
+	}
+	for i := range feed.Channel.Items {
+		feed.Channel.Items[i].Fields = fields[i]
+	}
+	return nil
+}
+
+// parseItemFields walks the feed and returns the fields of each
+// rss > channel > item, in document order.
+func parseItemFields(data []byte) ([][]Field, error) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+
+	var (
+		out   [][]Field
+		stack []xml.Name // open elements from the document root
+		text  []string   // direct text of the open elements
+	)
+	// inItem reports the depth of the current <item>, or 0.
+	inItem := 0
+
+	for {
+		tok, err := d.Token()
+		if errors.Is(err, io.EOF) {
+			return out, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			stack = append(stack, t.Name)
+			text = append(text, "")
+
+			if inItem == 0 {
+				if len(stack) == 3 && stack[0].Local == "rss" && stack[1].Local == "channel" && t.Name.Local == "item" {
+					inItem = len(stack)
+					out = append(out, nil)
+				}
+				continue
+			}
+
+			path := append([]xml.Name(nil), stack[inItem:]...)
+			for _, a := range t.Attr {
+				if a.Name.Space == "xmlns" || a.Name.Local == "xmlns" {
+					continue
+				}
+				out[len(out)-1] = append(out[len(out)-1], Field{Path: path, Attr: a.Name, Value: strings.TrimSpace(a.Value)})
+			}
+
+		case xml.CharData:
+			if len(text) > 0 {
+				text[len(text)-1] += string(t)
+			}
+
+		case xml.EndElement:
+			if inItem > 0 && len(stack) > inItem {
+				path := append([]xml.Name(nil), stack[inItem:]...)
+				value := strings.TrimSpace(text[len(text)-1])
+				out[len(out)-1] = append(out[len(out)-1], Field{Path: path, Value: value})
+			}
+			if len(stack) == inItem {
+				inItem = 0
+			}
+			stack = stack[:len(stack)-1]
+			text = text[:len(text)-1]
+		}
+	}
+}
+
+// fieldPath is a compiled field rule path such as "podcast:transcript@type".
+type fieldPath struct {
+	elems []fieldName
+	// attr is the zero fieldName for the element text.
+	attr fieldName
+}
+
+// fieldName is an element or attribute name of a field path.
+type fieldName struct {
+	// spaces lists the accepted namespace URIs ("" for no namespace).
+	spaces []string
+	local  string
+}
+
+func (n fieldName) matches(name xml.Name) bool {
+	if n.local != name.Local {
+		return false
+	}
+	if n.spaces == nil {
+		return name.Space == ""
+	}
+	for _, space := range n.spaces {
+		if space == name.Space {
+			return true
+		}
+	}
+	return false
+}
+
+// parseFieldPath parses "elem/elem@attr" where each name is local,
+// prefix:local (see knownPrefixes) or {uri}local.
+func parseFieldPath(s string) (fieldPath, error) {
+	var p fieldPath
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return p, errors.New("missing path")
+	}
+
+	// Split on "/" and "@" outside of {namespace-uri} sections.
+	var (
+		segments []string
+		attr     string
+		hasAttr  bool
+		depth    int
+		start    int
+	)
+	for i, r := range s {
+		switch {
+		case r == '{':
+			depth++
+		case r == '}':
+			depth--
+		case depth == 0 && r == '/' && !hasAttr:
+			segments = append(segments, s[start:i])
+			start = i + 1
+		case depth == 0 && r == '@' && !hasAttr:
+			segments = append(segments, s[start:i])
+			hasAttr = true
+			start = i + 1
+		}
+	}
+	if hasAttr {
+		attr = s[start:]
+	} else {
+		segments = append(segments, s[start:])
+	}
+
+	for _, e := range segments {
+		name, err := parseFieldName(e)
+		if err != nil {
+			return p, fmt.Errorf("invalid path %q: %w", s, err)
+		}
+		p.elems = append(p.elems, name)
+	}
+	if hasAttr {
+		name, err := parseFieldName(attr)
+		if err != nil {
+			return p, fmt.Errorf("invalid path %q: %w", s, err)
+		}
+		p.attr = name
+	}
+	return p, nil
+}
+
+func parseFieldName(s string) (fieldName, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return fieldName{}, errors.New("empty name")
+	}
+	if rest, ok := strings.CutPrefix(s, "{"); ok {
+		uri, local, ok := strings.Cut(rest, "}")
+		if !ok || local == "" {
+			return fieldName{}, fmt.Errorf("malformed name %q", s)
+		}
+		return fieldName{spaces: []string{uri}, local: local}, nil
+	}
+	if prefix, local, ok := strings.Cut(s, ":"); ok {
+		uris, known := knownPrefixes[prefix]
+		if !known {
+			return fieldName{}, fmt.Errorf("unknown prefix %q (use {namespace-uri}%s)", prefix, local)
+		}
+		return fieldName{spaces: uris, local: local}, nil
+	}
+	return fieldName{local: s}, nil
+}
+
+// values returns the values found at the path in the item fields.
+func (p fieldPath) values(item Item) []string {
+	var out []string
+	for _, f := range item.Fields {
+		if !p.attr.matches(f.Attr) || len(f.Path) != len(p.elems) {
+			continue
+		}
+		match := true
+		for i := range f.Path {
+			if !p.elems[i].matches(f.Path[i]) {
+				match = false
+				break
+			}
+		}
+		if match {
+			out = append(out, f.Value)
+		}
+	}
+	return out
+}
+
+func compileFieldRegex(rule config.Rule) (Matcher, error) {
+	// Keep items with a value at `path` matching the regex.
+	path, err := parseFieldPath(rule.Path)
+	if err != nil {
+		return nil, err
+	}
+	if err := requireValue(rule); err != nil {
+		return nil, err
+	}
+	opts, err := compileTextOptions(rule, true)
+	if err != nil {
+		return nil, err
+	}
+	re, err := opts.compileRegexp(rule.Value)
+	if err != nil {
+		return nil, err
+	}
+	return func(item Item, _ *EvalContext) (bool, error) {
+		for _, v := range path.values(item) {
+			if re.MatchString(opts.fold(v)) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}, nil
+}
@@ -0,0 +1,152 @@
+package rss
+
+import (
+	"testing"
+
+	"rss-proxy/config"
+)
+
+const fieldsRSS = `
+<rss xmlns:podcast="https://podcastindex.org/namespace/1.0"
+     xmlns:media="http://search.yahoo.com/mrss/"
+     xmlns:x="https://example.com/ns">
+  <channel>
+    <title>Fields</title>
+    <item>
+      <title>Transcribed</title>
+      <podcast:transcript url="https://e.x/1.vtt" type="text/vtt"/>
+      <x:segment>morning</x:segment>
+    </item>
+    <item>
+      <title>Video</title>
+      <media:group>
+        <media:content url="https://e.x/2.mp4" medium="video"/>
+      </media:group>
+    </item>
+  </channel>
+</rss>
+`
+
+func TestParseItemFields(t *testing.T) {
+	feed, err := Parse([]byte(fieldsRSS))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path, err := parseFieldPath("podcast:transcript@type")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := path.values(feed.Channel.Items[0]); len(v) != 1 || v[0] != "text/vtt" {
+		t.Fatalf("unexpected values: %v", v)
+	}
+
+	path, err = parseFieldPath("media:group/media:content@medium")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := path.values(feed.Channel.Items[1]); len(v) != 1 || v[0] != "video" {
+		t.Fatalf("unexpected values: %v", v)
+	}
+
+	path, err = parseFieldPath("title")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := path.values(feed.Channel.Items[1]); len(v) != 1 || v[0] != "Video" {
+		t.Fatalf("unexpected values: %v", v)
+	}
+}
+
+func TestFieldPathAlternateNamespace(t *testing.T) {
+	// Older podcast feeds bind podcast: to the namespace GitHub page.
+	feed, err := Parse([]byte(`
+<rss xmlns:podcast="https://github.com/Podcastindex-org/podcast-namespace/blob/main/docs/1.0.md">
+  <channel>
+    <item>
+      <title>Transcribed</title>
+      <podcast:transcript url="https://e.x/1.vtt" type="text/vtt"/>
+    </item>
+  </channel>
+</rss>
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path, err := parseFieldPath("podcast:transcript@type")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := path.values(feed.Channel.Items[0]); len(v) != 1 || v[0] != "text/vtt" {
+		t.Fatalf("unexpected values: %v", v)
+	}
+
+	// An explicit URI only matches itself.
+	path, err = parseFieldPath("{https://podcastindex.org/namespace/1.0}transcript@type")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := path.values(feed.Channel.Items[0]); len(v) != 0 {
+		t.Fatalf("unexpected values: %v", v)
+	}
+}
+
+func TestFieldRegexRule(t *testing.T) {
+	feed, err := Parse([]byte(fieldsRSS))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := ApplyRules(feed, []config.Rule{{Type: "field_regex", Path: "podcast:transcript@type", Value: "^text/"}})
+	if len(out.Channel.Items) != 1 || out.Channel.Items[0].Title != "Transcribed" {
+		t.Fatalf("expected only Transcribed, got %+v", out.Channel.Items)
+	}
+
+	out = ApplyRules(feed, []config.Rule{{Type: "field_regex", Path: "{https://example.com/ns}segment", Value: "morning"}})
+	if len(out.Channel.Items) != 1 || out.Channel.Items[0].Title != "Transcribed" {
+		t.Fatalf("expected only Transcribed, got %+v", out.Channel.Items)
+	}
+
+	for _, rule := range []config.Rule{
+		{Type: "field_regex", Value: "x"},
+		{Type: "field_regex", Path: "x:segment", Value: "x"},
+		{Type: "field_regex", Path: "podcast:transcript@type"},
+	} {
+		if _, err := CompileRules("legend", []config.Rule{rule}); err == nil {
+			t.Fatalf("%+v: expected an error", rule)
+		}
+	}
+}
+
+func TestParseIgnoresFieldErrors(t *testing.T) {
+	// xml.Unmarshal stops after the root element; the fields pass reads on.
+	feed, err := Parse([]byte(fieldsRSS + "<"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(feed.Channel.Items) != 2 || feed.Channel.Items[0].Fields != nil {
+		t.Fatalf("unexpected items: %+v", feed.Channel.Items)
+	}
+}
+
+func TestNeedsFields(t *testing.T) {
+	field := config.Rule{Type: "field_regex", Path: "title", Value: "x"}
+	for _, tt := range []struct {
+		rules []config.Rule
+		want  bool
+	}{
+		{[]config.Rule{{Type: "title_contains", Value: "x"}, {Type: "keep_latest", Count: 1}}, false},
+		{[]config.Rule{field}, true},
+		{[]config.Rule{{Any: []config.Rule{{Type: "title_contains", Value: "x"}, field}}}, true},
+		{[]config.Rule{{Not: &field}}, true},
+	} {
+		rs, err := CompileFeed(config.Feed{ID: "legend", Rules: tt.rules})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rs.needsFields != tt.want {
+			t.Fatalf("%+v: needsFields = %v, want %v", tt.rules, rs.needsFields, tt.want)
+		}
+	}
+}
@@ -28,6 +28,10 @@ type FilterXMLOptions struct {
 	// a different URL (migration hint). Podcast apps may refuse to subscribe to the proxy
 	// feed if the tag indicates the feed lives elsewhere.
 	RewriteNewFeedURL string
+
+	// KeepItems, when non-nil, selects items by position (0-based, in document
+	// order) instead of by title. Titles alone can't tell duplicate items apart.
+	KeepItems map[int]bool
 }
 
 func xmlEscapeText(s string) string {
@@ -56,6 +60,11 @@ func normalizeTitleBytes(b []byte) string {
 	return strings.TrimSpace(s)
 }
 
+// countXMLItems returns the number of <item> elements the byte-level filter sees.
+func countXMLItems(raw []byte) int {
+	return len(reItem.FindAllIndex(raw, -1))
+}
+
 // FilterXML filters the original RSS XML by keeping only items whose <title> matches keepTitles.
 //
 // For advanced behaviors, use FilterXMLWithOptions.
@@ -82,15 +91,18 @@ func FilterXMLWithOptions(raw []byte, keepTitles map[string]bool, opts FilterXML
 	var buf bytes.Buffer
 	last := 0
 
-	for _, m := range matches {
+	for i, m := range matches {
 		start, end := m[0], m[1]
 
 		// Write everything between previous item and this item (channel metadata etc.)
 		buf.Write(raw[last:start])
 
 		item := raw[start:end]
-		titleMatch := reTitle.FindSubmatch(item)
-		if titleMatch != nil {
+		if opts.KeepItems != nil {
+			if opts.KeepItems[i] {
+				buf.Write(item)
+			}
+		} else if titleMatch := reTitle.FindSubmatch(item); titleMatch != nil {
 			title := normalizeTitleBytes(titleMatch[1])
 			if keepTitles[title] {
 				buf.Write(item)
@@ -130,3 +130,20 @@ func TestFilterXMLNoRewriteKeepsUpstreamNewFeedURL(t *testing.T) {
 		t.Fatal("expected upstream itunes:new-feed-url to be preserved when no rewrite requested")
 	}
 }
+
+func TestFilterXMLKeepItemsByPosition(t *testing.T) {
+	out, err := FilterXMLWithOptions([]byte(sampleRSS), nil, FilterXMLOptions{
+		KeepItems: map[int]bool{0: true, 3: true},
+	})
+	if err != nil {
+		t.Fatalf("FilterXMLWithOptions error: %v", err)
+	}
+
+	s := string(out)
+	if !strings.Contains(s, "<title>KEEP ME</title>") || !strings.Contains(s, "<title>DROP ME</title>") {
+		t.Fatal("expected items 0 and 3 to be kept")
+	}
+	if strings.Contains(s, "CDATA KEEP") || strings.Contains(s, "Fish &amp; Chips") {
+		t.Fatal("expected items 1 and 2 to be dropped")
+	}
+}
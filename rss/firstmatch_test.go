@@ -0,0 +1,98 @@
+package rss
+
+import (
+	"strings"
+	"testing"
+
+	"rss-proxy/config"
+)
+
+func TestFirstMatchMode(t *testing.T) {
+	feed := RSS{
+		Channel: Channel{
+			Items: []Item{
+				{Title: "Jour 640"},
+				{Title: "[REDIFF] Jour 100"},
+				{Title: "[REDIFF] Jour 200 – version augmentée"},
+				{Title: "Annonce"},
+			},
+		},
+	}
+
+	// Drop reruns, but keep reruns of extended versions.
+	rules := []config.Rule{
+		{Type: "title_contains", Value: "augmentée", Action: ActionKeep},
+		{Type: "title_contains", Value: "REDIFF", Action: ActionDrop},
+		{Type: "title_contains", Value: "Jour", Action: ActionKeep},
+	}
+
+	for _, tt := range []struct {
+		defaultAction string
+		want          string
+	}{
+		{"", "Jour 640|[REDIFF] Jour 200 – version augmentée|Annonce"},
+		{ActionDrop, "Jour 640|[REDIFF] Jour 200 – version augmentée"},
+	} {
+		rs, err := CompileFeed(config.Feed{ID: "legend", Mode: ModeFirstMatch, DefaultAction: tt.defaultAction, Rules: rules})
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := rs.Apply(feed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, item := range out.Channel.Items {
+			got = append(got, item.Title)
+		}
+		if strings.Join(got, "|") != tt.want {
+			t.Fatalf("default_action %q: got %q, want %q", tt.defaultAction, got, tt.want)
+		}
+
+		d := rs.Explain(feed)
+		if d[2].KeptBy == nil || d[2].KeptBy.Index != 0 || d[1].RejectedBy == nil || d[1].RejectedBy.Index != 1 {
+			t.Fatalf("unexpected decisions: %+v", d)
+		}
+	}
+}
+
+func TestFirstMatchRuleErrors(t *testing.T) {
+	feed := RSS{Channel: Channel{Items: []Item{{Title: "Broken", Duration: "n/a"}}}}
+	rules := []config.Rule{{Type: "length_max", Value: "1h", Action: ActionDrop}}
+
+	for policy, want := range map[string]int{OnRuleErrorKeep: 1, OnRuleErrorDrop: 0} {
+		rs, err := CompileFeed(config.Feed{ID: "legend", Mode: ModeFirstMatch, DefaultAction: ActionDrop, OnRuleError: policy, Rules: rules})
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := rs.Apply(feed)
+		if err != nil || len(out.Channel.Items) != want {
+			t.Fatalf("%s: expected %d items, got %+v, %v", policy, want, out.Channel.Items, err)
+		}
+	}
+}
+
+func TestFirstMatchValidation(t *testing.T) {
+	rule := config.Rule{Type: "title_contains", Value: "X"}
+	withAction := config.Rule{Type: "title_contains", Value: "X", Action: ActionKeep}
+
+	cases := map[string]config.Feed{
+		`invalid mode "any"`:              {Mode: "any"},
+		"default_action requires mode":    {DefaultAction: ActionDrop},
+		"rules[0]: action requires mode":  {Rules: []config.Rule{withAction}},
+		"rules[0]: missing action":        {Mode: ModeFirstMatch, Rules: []config.Rule{rule}},
+		`invalid action "skip"`:           {Mode: ModeFirstMatch, Rules: []config.Rule{{Type: "title_contains", Value: "X", Action: "skip"}}},
+		"feed-level rules take no action": {Mode: ModeFirstMatch, Rules: []config.Rule{{Type: "keep_latest", Count: 1, Action: ActionKeep}}},
+		"rules[0].any[0]: action is only allowed on top-level rules": {
+			Mode:  ModeFirstMatch,
+			Rules: []config.Rule{{Any: []config.Rule{withAction}, Action: ActionKeep}},
+		},
+	}
+	for want, feed := range cases {
+		feed.ID = "legend"
+		_, err := CompileFeed(feed)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected error containing %q, got %v", want, err)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package rss
 
 import (
+	"fmt"
+	"math"
 	"net/http"
 	"strings"
 	"time"
@@ -11,6 +13,7 @@ import (
 // Handler serves a filtered RSS feed for a single podcast.
 type Handler struct {
 	feed  config.Feed
+	rules *RuleSet
 	cache *HTTPCache
 	// baseURL is the externally reachable base URL (from config.server.base_url).
 	// When set, it is used to rewrite <itunes:new-feed-url> so podcast apps keep
@@ -18,21 +21,39 @@ type Handler struct {
 	baseURL string
 }
 
+// NewFeedHandler creates a handler with an injected HTTP cache and an external base URL.
+//
+// The feed rules are compiled once here; invalid rules are reported as an error
+// naming the feed ID and the rule index.
+func NewFeedHandler(feed config.Feed, cache *HTTPCache, baseURL string) (http.Handler, error) {
+	rules, err := CompileFeed(feed)
+	if err != nil {
+		return nil, err
+	}
+	return &Handler{
+		feed:    feed,
+		rules:   rules,
+		cache:   cache,
+		baseURL: baseURL,
+	}, nil
+}
+
 // NewHandler creates a handler with an injected HTTP cache.
+//
+// It panics if the feed rules are invalid; use NewFeedHandler to get an error instead.
 func NewHandler(feed config.Feed, cache *HTTPCache) http.Handler {
-	return &Handler{
-		feed:  feed,
-		cache: cache,
-	}
+	return NewHandlerWithBaseURL(feed, cache, "")
 }
 
 // NewHandlerWithBaseURL creates a handler with an injected HTTP cache and an external base URL.
+//
+// It panics if the feed rules are invalid; use NewFeedHandler to get an error instead.
 func NewHandlerWithBaseURL(feed config.Feed, cache *HTTPCache, baseURL string) http.Handler {
-	return &Handler{
-		feed:    feed,
-		cache:   cache,
-		baseURL: baseURL,
+	h, err := NewFeedHandler(feed, cache, baseURL)
+	if err != nil {
+		panic(err)
 	}
+	return h
 }
 
 // NewHandlerWithDefaultCache creates a handler with a default in-memory HTTP cache.
@@ -80,7 +101,7 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	)
 
 	// Parse RSS for rule evaluation only (read-only)
-	parsed, err := Parse(raw)
+	parsed, err := parseItems(raw)
 	if err != nil {
 		Logger.Error("failed to parse feed",
 			"feed_id", h.feed.ID,
@@ -90,13 +111,32 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Fields take a second pass, only for the rules reading them. Rules on
+	// fields see none if it fails.
+	if h.rules.needsFields {
+		if err := setItemFields(&parsed, raw); err != nil {
+			Logger.Warn("failed to read item fields",
+				"feed_id", h.feed.ID,
+				"error", err,
+			)
+		}
+	}
+
 	Logger.Info("parsed feed",
 		"feed_id", h.feed.ID,
 		"items_total", len(parsed.Channel.Items),
 	)
 
-	// Apply filtering rules
-	filtered := ApplyRules(parsed, h.feed.Rules)
+	// Apply precompiled filtering rules
+	filtered, ctx, err := h.rules.apply(parsed)
+	if err != nil {
+		Logger.Error("failed to apply rules",
+			"feed_id", h.feed.ID,
+			"error", err,
+		)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
 	Logger.Info("rules applied",
 		"feed_id", h.feed.ID,
@@ -104,10 +144,19 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		"items_dropped", len(parsed.Channel.Items)-len(filtered.Channel.Items),
 	)
 
-	// Build allow-list of titles to keep
+	// Build allow-lists of items to keep. Positions are used when the byte-level
+	// filter sees the same items as the parser, so that duplicates sharing a
+	// title can be told apart; titles are the fallback.
 	keepTitles := make(map[string]bool, len(filtered.Channel.Items))
+	var keepItems map[int]bool
+	if countXMLItems(raw) == len(parsed.Channel.Items) {
+		keepItems = make(map[int]bool, len(filtered.Channel.Items))
+	}
 	for _, item := range filtered.Channel.Items {
 		keepTitles[item.Title] = true
+		if keepItems != nil {
+			keepItems[item.index] = true
+		}
 	}
 
 	// Filter original XML at item level (byte-for-byte)
@@ -115,6 +164,7 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// Also rewrite <itunes:new-feed-url> if configured.
 	xmlOut, err := FilterXMLWithOptions(raw, keepTitles, FilterXMLOptions{
 		RewriteNewFeedURL: feedURLFromBase(h.baseURL, h.feed.ID),
+		KeepItems:         keepItems,
 	})
 	if err != nil {
 		Logger.Error("failed to filter xml",
@@ -125,8 +175,17 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Let clients cache the feed for 15 minutes, or until the next delayed
+	// item becomes visible.
+	maxAge := 900
+	if !ctx.nextVisible.IsZero() {
+		if secs := int(math.Ceil(ctx.nextVisible.Sub(ctx.now).Seconds())); secs < maxAge {
+			maxAge = secs
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
-	w.Header().Set("Cache-Control", "public, max-age=900")
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", maxAge))
 	w.WriteHeader(http.StatusOK)
 
 	if _, err := w.Write(xmlOut); err != nil {
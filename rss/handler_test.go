@@ -5,6 +5,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"rss-proxy/config"
 )
@@ -63,3 +64,95 @@ func TestHandlerEndToEnd(t *testing.T) {
 		t.Fatal("expected itunes:new-feed-url to be rewritten to proxy URL")
 	}
 }
+
+func TestHandlerRemovesDuplicateCopies(t *testing.T) {
+	const duplicatedRSS = `<rss><channel><title>Dup</title>
+<item><title>Episode 2</title><guid>ep-2</guid><pubDate>Fri, 06 Dec 2024 06:00:00 +0000</pubDate><description>copy</description></item>
+<item><title>Episode 1</title><guid>ep-1</guid></item>
+<item><title>Episode 2</title><guid>ep-2</guid><pubDate>Wed, 04 Dec 2024 06:00:00 +0000</pubDate><description>original</description></item>
+</channel></rss>`
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(duplicatedRSS))
+	}))
+	defer srv.Close()
+
+	cache := NewHTTPCache(0)
+	cache.client = srv.Client()
+
+	handler := NewHandler(config.Feed{
+		ID:     "dup",
+		Source: srv.URL,
+		Rules:  []config.Rule{{Type: "dedupe", Key: "guid", Keep: "first"}},
+	}, cache)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/rss/dup.xml", nil))
+
+	body := w.Body.String()
+	if strings.Count(body, "<title>Episode 2</title>") != 1 {
+		t.Fatalf("expected a single Episode 2 copy, got:\n%s", body)
+	}
+	if !strings.Contains(body, "copy") || strings.Contains(body, "original") {
+		t.Fatal("expected the first copy to be kept")
+	}
+	if !strings.Contains(body, "<title>Episode 1</title>") {
+		t.Fatal("expected Episode 1 to be kept")
+	}
+}
+
+func TestHandlerShortensMaxAgeForDelayedItems(t *testing.T) {
+	const delayedRSS = `<rss><channel><title>News</title>
+<item><title>Fresh</title><pubDate>Fri, 13 Dec 2024 11:55:00 +0000</pubDate></item>
+<item><title>Settled</title><pubDate>Fri, 13 Dec 2024 05:00:00 +0000</pubDate></item>
+</channel></rss>`
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(delayedRSS))
+	}))
+	defer srv.Close()
+
+	cache := NewHTTPCache(0)
+	cache.client = srv.Client()
+
+	handler, err := NewFeedHandler(config.Feed{
+		ID:     "news",
+		Source: srv.URL,
+		Rules:  []config.Rule{{Type: "delay", Value: "1h"}},
+	}, cache, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 12, 13, 12, 0, 0, 0, time.UTC)
+	handler.(*Handler).rules.now = func() time.Time { return now }
+
+	serve := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "/rss/news.xml", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("unexpected status %d", w.Code)
+		}
+		return w
+	}
+
+	// Fresh becomes visible at 12:55, in 55 minutes: more than 15.
+	w := serve()
+	if got := w.Header().Get("Cache-Control"); got != "public, max-age=900" {
+		t.Fatalf("unexpected Cache-Control %q", got)
+	}
+	if strings.Contains(w.Body.String(), "<title>Fresh</title>") {
+		t.Fatal("Fresh should still be hidden")
+	}
+
+	now = now.Add(50 * time.Minute)
+	if got := serve().Header().Get("Cache-Control"); got != "public, max-age=300" {
+		t.Fatalf("unexpected Cache-Control %q", got)
+	}
+
+	now = now.Add(5 * time.Minute)
+	if !strings.Contains(serve().Body.String(), "<title>Fresh</title>") {
+		t.Fatal("Fresh should be visible")
+	}
+}
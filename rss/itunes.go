@@ -0,0 +1,112 @@
+package rss
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"rss-proxy/config"
+)
+
+// Values of <itunes:episodeType>.
+var episodeTypes = map[string]bool{
+	"full":    true,
+	"trailer": true,
+	"bonus":   true,
+}
+
+// episodeType returns the item <itunes:episodeType>, lowercased.
+// Apple treats a missing episode type as "full".
+func episodeType(item Item) string {
+	t := strings.ToLower(strings.TrimSpace(item.EpisodeType))
+	if t == "" {
+		return "full"
+	}
+	return t
+}
+
+// season returns the item <itunes:season> number.
+func season(item Item) (int, error) {
+	s := strings.TrimSpace(item.Season)
+	if s == "" {
+		return 0, errors.New("no season provided")
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("can't parse season %q", s)
+	}
+	return n, nil
+}
+
+func compileEpisodeType(rule config.Rule) (Matcher, error) {
+	// Keep items whose episode type is one of the values (full, trailer, bonus).
+	values, err := ruleValues(rule)
+	if err != nil {
+		return nil, err
+	}
+	keep := make(map[string]bool, len(values))
+	for _, v := range values {
+		v = strings.ToLower(v)
+		if !episodeTypes[v] {
+			return nil, fmt.Errorf("invalid episode type %q (want full, trailer or bonus)", v)
+		}
+		keep[v] = true
+	}
+
+	return func(item Item, _ *EvalContext) (bool, error) {
+		return keep[episodeType(item)], nil
+	}, nil
+}
+
+func compileSeasonMin(rule config.Rule) (Matcher, error) {
+	// Keep items from season `min` onwards.
+	if rule.Min <= 0 {
+		return nil, errors.New("missing min (must be > 0)")
+	}
+	return func(item Item, _ *EvalContext) (bool, error) {
+		n, err := season(item)
+		if err != nil {
+			return false, err
+		}
+		return n >= rule.Min, nil
+	}, nil
+}
+
+func compileSeasonMax(rule config.Rule) (Matcher, error) {
+	// Keep items up to season `max`.
+	if rule.Max <= 0 {
+		return nil, errors.New("missing max (must be > 0)")
+	}
+	return func(item Item, _ *EvalContext) (bool, error) {
+		n, err := season(item)
+		if err != nil {
+			return false, err
+		}
+		return n <= rule.Max, nil
+	}, nil
+}
+
+func compileSeasonIn(rule config.Rule) (Matcher, error) {
+	// Keep items whose season is one of the values.
+	values, err := ruleValues(rule)
+	if err != nil {
+		return nil, err
+	}
+	keep := make(map[int]bool, len(values))
+	for _, v := range values {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid season %q", v)
+		}
+		keep[n] = true
+	}
+
+	return func(item Item, _ *EvalContext) (bool, error) {
+		n, err := season(item)
+		if err != nil {
+			return false, err
+		}
+		return keep[n], nil
+	}, nil
+}
@@ -0,0 +1,87 @@
+package rss
+
+import (
+	"testing"
+
+	"rss-proxy/config"
+)
+
+const itunesRSS = `
+<rss xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd">
+  <channel>
+    <title>Seasons</title>
+    <item>
+      <title>Trailer</title>
+      <itunes:episodeType>trailer</itunes:episodeType>
+      <itunes:season>2</itunes:season>
+    </item>
+    <item>
+      <title>S2E1</title>
+      <itunes:episodeType>full</itunes:episodeType>
+      <itunes:season>2</itunes:season>
+    </item>
+    <item>
+      <title>S1E1</title>
+      <itunes:season>1</itunes:season>
+    </item>
+    <item>
+      <title>Bonus</title>
+      <itunes:episodeType>bonus</itunes:episodeType>
+    </item>
+  </channel>
+</rss>
+`
+
+func TestParseITunesEpisodeTypeAndSeason(t *testing.T) {
+	feed, err := Parse([]byte(itunesRSS))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := feed.Channel.Items[0]
+	if first.EpisodeType != "trailer" || first.Season != "2" {
+		t.Fatalf("unexpected itunes fields: %+v", first)
+	}
+}
+
+func TestEpisodeTypeAndSeasonRules(t *testing.T) {
+	feed, err := Parse([]byte(itunesRSS))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A missing episode type counts as full.
+	out := ApplyRules(feed, []config.Rule{{Type: "episode_type", Value: "full"}})
+	if len(out.Channel.Items) != 2 || out.Channel.Items[0].Title != "S2E1" || out.Channel.Items[1].Title != "S1E1" {
+		t.Fatalf("expected S2E1 and S1E1, got %+v", out.Channel.Items)
+	}
+
+	rs, err := CompileFeed(config.Feed{
+		ID:          "seasons",
+		OnRuleError: OnRuleErrorDrop,
+		Rules: []config.Rule{
+			{Type: "season_in", Values: []string{"2", "3"}},
+			{Not: &config.Rule{Type: "episode_type", Value: "trailer"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err = rs.Apply(feed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Channel.Items) != 1 || out.Channel.Items[0].Title != "S2E1" {
+		t.Fatalf("expected only S2E1, got %+v", out.Channel.Items)
+	}
+
+	out = ApplyRules(feed, []config.Rule{{Type: "season_max", Max: 1}, {Type: "season_min", Min: 1}})
+	// Bonus has no season and is kept by the default on_rule_error policy.
+	if len(out.Channel.Items) != 2 || out.Channel.Items[0].Title != "S1E1" {
+		t.Fatalf("expected S1E1 and Bonus, got %+v", out.Channel.Items)
+	}
+
+	if _, err := CompileRules("seasons", []config.Rule{{Type: "episode_type", Value: "teaser"}}); err == nil {
+		t.Fatal("expected invalid episode type to be rejected")
+	}
+}
@@ -12,10 +12,74 @@ type Channel struct {
 	Items []Item `xml:"item"`
 }
 
+// Item is the read-only view of an <item> used for rule evaluation.
+//
+// Numeric-looking fields other than Episode are kept as strings so that a
+// malformed value doesn't make the whole feed unparseable.
+//
+// encoding/xml matches an unqualified tag such as "author" against elements of
+// any namespace, so namespaced fields (itunes:title, itunes:author,
+// media:category, atom:link) must be declared before their unqualified
+// namesakes to keep them apart.
 type Item struct {
-	Title       string `xml:"title"`
-	PubDate     string `xml:"pubDate"`
-	Episode     int    `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd episode"`
-	Duration    string `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd duration"`
-	Description string `xml:"description"`
+	ITunesTitle string     `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd title"`
+	Title       string     `xml:"title"`
+	GUID        GUID       `xml:"guid"`
+	PubDate     string     `xml:"pubDate"`
+	Episode     int        `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd episode"`
+	Duration    string     `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd duration"`
+	EpisodeType string     `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd episodeType"`
+	Season      string     `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd season"`
+	Description string     `xml:"description"`
+	Enclosure   *Enclosure `xml:"enclosure"`
+
+	MediaCategories  []string         `xml:"http://search.yahoo.com/mrss/ category"`
+	ITunesCategories []ITunesCategory `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd category"`
+	Categories       []string         `xml:"category"`
+
+	Keywords string `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd keywords"`
+
+	ITunesAuthor string `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd author"`
+	Author       string `xml:"author"`
+	Creator      string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+
+	AtomLinks []AtomLink `xml:"http://www.w3.org/2005/Atom link"`
+	Link      string     `xml:"link"`
+
+	// Fields lists every child element and attribute of the item, for rules
+	// on fields not modeled above. Set by Parse.
+	Fields []Field `xml:"-"`
+
+	// index is the item position in the upstream feed, set by Parse.
+	index int
+}
+
+// ITunesCategory is an <itunes:category>, named by its text attribute.
+type ITunesCategory struct {
+	Text string `xml:"text,attr"`
+}
+
+// GUID is the unique identifier of an item.
+//
+// IsPermaLink is kept as written: RSS 2.0 reads an empty value as "true",
+// meaning Value is the item URL.
+type GUID struct {
+	Value       string `xml:",chardata"`
+	IsPermaLink string `xml:"isPermaLink,attr"`
+}
+
+// AtomLink is an <atom:link> element of an item.
+type AtomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+}
+
+// Enclosure is the media file attached to an item.
+//
+// Length is kept as a string: feeds often leave it empty or put junk in it,
+// which must not make the whole feed unparseable.
+type Enclosure struct {
+	URL    string `xml:"url,attr"`
+	Type   string `xml:"type,attr"`
+	Length string `xml:"length,attr"`
 }
@@ -0,0 +1,101 @@
+package rss
+
+import (
+	"strings"
+	"testing"
+
+	"rss-proxy/config"
+)
+
+func TestParseKeepsNamespacedFieldsApart(t *testing.T) {
+	const data = `
+<rss xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd"
+     xmlns:dc="http://purl.org/dc/elements/1.1/"
+     xmlns:atom="http://www.w3.org/2005/Atom"
+     xmlns:media="http://search.yahoo.com/mrss/">
+  <channel>
+    <title>Network</title>
+    <item>
+      <title>Real title</title>
+      <itunes:title>Short title</itunes:title>
+      <author>desk@example.com (News Desk)</author>
+      <itunes:author>Jane Doe</itunes:author>
+      <dc:creator>John Smith</dc:creator>
+      <link>https://example.com/segment/morning/42</link>
+      <atom:link rel="alternate" href="https://example.com/alt/42"/>
+      <guid isPermaLink="false">urn:morning:42</guid>
+      <category>News</category>
+      <media:category>sports/football</media:category>
+      <itunes:category text="Sports"/>
+    </item>
+  </channel>
+</rss>
+`
+
+	feed, err := Parse([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	item := feed.Channel.Items[0]
+	if item.Title != "Real title" || item.ITunesTitle != "Short title" {
+		t.Fatalf("titles mixed up: %q / %q", item.Title, item.ITunesTitle)
+	}
+	if item.Author != "desk@example.com (News Desk)" {
+		t.Fatalf("unexpected author: %q", item.Author)
+	}
+	if item.ITunesAuthor != "Jane Doe" {
+		t.Fatalf("unexpected itunes:author: %q", item.ITunesAuthor)
+	}
+	if item.Creator != "John Smith" {
+		t.Fatalf("unexpected dc:creator: %q", item.Creator)
+	}
+	if item.Link != "https://example.com/segment/morning/42" {
+		t.Fatalf("unexpected link: %q", item.Link)
+	}
+	if len(item.AtomLinks) != 1 || item.AtomLinks[0].Href != "https://example.com/alt/42" {
+		t.Fatalf("unexpected atom:link: %+v", item.AtomLinks)
+	}
+	if item.GUID.Value != "urn:morning:42" || item.GUID.IsPermaLink != "false" {
+		t.Fatalf("unexpected guid: %+v", item.GUID)
+	}
+	if len(item.Categories) != 1 || item.Categories[0] != "News" {
+		t.Fatalf("unexpected categories: %q", item.Categories)
+	}
+	if len(item.MediaCategories) != 1 || item.MediaCategories[0] != "sports/football" {
+		t.Fatalf("unexpected media:category: %q", item.MediaCategories)
+	}
+	if len(item.ITunesCategories) != 1 || item.ITunesCategories[0].Text != "Sports" {
+		t.Fatalf("unexpected itunes:category: %+v", item.ITunesCategories)
+	}
+}
+
+func TestGUIDAndLinkRules(t *testing.T) {
+	feed := RSS{
+		Channel: Channel{
+			Items: []Item{
+				{Title: "A", GUID: GUID{Value: "https://example.com/segment/morning/1"}, Link: "https://example.com/1"},
+				{Title: "B", GUID: GUID{Value: "urn:evening:2", IsPermaLink: "false"}, Link: "https://example.com/segment/morning/2"},
+				{Title: "C", GUID: GUID{Value: "urn:evening:3", IsPermaLink: "false"}},
+			},
+		},
+	}
+
+	for _, tt := range []struct {
+		rule config.Rule
+		want string
+	}{
+		{config.Rule{Type: "guid_regex", Value: `/segment/morning/`}, "A"},
+		{config.Rule{Type: "link_regex", Value: `/segment/morning/`}, "B"},
+		{config.Rule{Expr: `matches(guid, "^urn:evening:") && link == ""`}, "C"},
+	} {
+		out := ApplyRules(feed, []config.Rule{tt.rule})
+		var got []string
+		for _, item := range out.Channel.Items {
+			got = append(got, item.Title)
+		}
+		if strings.Join(got, ",") != tt.want {
+			t.Fatalf("%+v: got %v, want %s", tt.rule, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package rss
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"rss-proxy/config"
+)
+
+// builtinMarkers are the named multipart marker patterns. Each pattern
+// captures the part number in `part` and the number of parts in `total`.
+var builtinMarkers = map[string]*regexp.Regexp{
+	// [1/2], [2/2], [10/10]
+	"brackets": regexp.MustCompile(`\[(?P<part>\d+)\s*/\s*(?P<total>\d+)\]`),
+	// (1/2)
+	"parens": regexp.MustCompile(`\((?P<part>\d+)\s*/\s*(?P<total>\d+)\)`),
+	// Part 2 of 2
+	"part_of": regexp.MustCompile(`(?i)\bpart\s+(?P<part>\d+)\s+of\s+(?P<total>\d+)\b`),
+	// Partie 1 sur 3
+	"partie_sur": regexp.MustCompile(`(?i)\bpartie\s+(?P<part>\d+)\s+sur\s+(?P<total>\d+)\b`),
+	// Épisode 3/4, Episode 3 / 4, Ep. 3/4, not "Deep sleep 1/2" (\b is
+	// ASCII-only: it would not bound "é").
+	"episode_slash": regexp.MustCompile(`(?i)(?:^|[^\p{L}\p{N}])(?:épisode|episode|ép\.?|ep\.?)\s*(?P<part>\d+)\s*/\s*(?P<total>\d+)`),
+}
+
+// commonMarkers is the order in which built-in markers are tried by the
+// "common" marker set.
+var commonMarkers = []string{"brackets", "parens", "part_of", "partie_sur", "episode_slash"}
+
+// defaultMarkers are used when a rule doesn't configure markers.
+var defaultMarkers = []*regexp.Regexp{builtinMarkers["brackets"]}
+
+// compileMarkers resolves the `markers` of a rule. Each entry is a built-in
+// marker name, "common" for every built-in marker, or a custom regex with
+// `part` and `total` named groups.
+func compileMarkers(names []string) ([]*regexp.Regexp, error) {
+	if len(names) == 0 {
+		return defaultMarkers, nil
+	}
+
+	var out []*regexp.Regexp
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "common" {
+			for _, n := range commonMarkers {
+				out = append(out, builtinMarkers[n])
+			}
+			continue
+		}
+		if re, ok := builtinMarkers[name]; ok {
+			out = append(out, re)
+			continue
+		}
+
+		re, err := regexp.Compile(name)
+		if err != nil {
+			return nil, fmt.Errorf("marker %q is neither a built-in marker nor a valid regex: %w", name, err)
+		}
+		groups := re.SubexpNames()
+		if !slices.Contains(groups, "part") || !slices.Contains(groups, "total") {
+			return nil, fmt.Errorf("marker %q must have (?P<part>...) and (?P<total>...) groups", name)
+		}
+		out = append(out, re)
+	}
+	return out, nil
+}
+
+// part describes the multipart marker found in a title.
+type part struct {
+	// index is x and total is y in "[x/y]".
+	index, total int
+	// base identifies the series: the title without its marker, normalized.
+	base string
+}
+
+// parsePart extracts the multipart marker of a title, trying markers in order.
+func parsePart(title string, markers []*regexp.Regexp) (part, bool) {
+	for _, re := range markers {
+		loc := re.FindStringSubmatchIndex(title)
+		if loc == nil {
+			continue
+		}
+
+		// Custom markers may leave a group unmatched or capture a non-number:
+		// that's not a marker, try the next one.
+		group := func(name string) (int, bool) {
+			i := 2 * re.SubexpIndex(name)
+			if loc[i] < 0 {
+				return 0, false
+			}
+			n, err := strconv.Atoi(title[loc[i]:loc[i+1]])
+			return n, err == nil
+		}
+		index, ok := group("part")
+		if !ok {
+			continue
+		}
+		total, ok := group("total")
+		if !ok {
+			continue
+		}
+
+		return part{
+			index: index,
+			total: total,
+			base:  normalizeTitleKey(title[:loc[0]] + " " + title[loc[1]:]),
+		}, true
+	}
+	return part{}, false
+}
+
+func compileTitleFractionEquals(rule config.Rule) (Matcher, error) {
+	// Keep only items where [x/y] and x == y
+	markers, err := compileMarkers(rule.Markers)
+	if err != nil {
+		return nil, err
+	}
+	return func(item Item, _ *EvalContext) (bool, error) {
+		p, ok := parsePart(item.Title, markers)
+		if !ok {
+			return false, nil
+		}
+		return p.index == p.total, nil
+	}, nil
+}
+
+func compileMultipartComplete(rule config.Rule) (selector, error) {
+	// Hold back every part of a multipart series until all parts are out,
+	// then keep them all (keep: all) or only the last one (keep: last).
+	// Items without a multipart marker are left alone.
+	markers, err := compileMarkers(rule.Markers)
+	if err != nil {
+		return nil, err
+	}
+
+	var lastOnly bool
+	switch rule.Keep {
+	case "", "all":
+	case "last":
+		lastOnly = true
+	default:
+		return nil, fmt.Errorf("invalid keep %q (want all or last)", rule.Keep)
+	}
+
+	return func(items []Item, _ *EvalContext) []Item {
+		type series struct {
+			total int
+			seen  map[int]bool
+		}
+		parts := make([]part, len(items))
+		isPart := make([]bool, len(items))
+		groups := make(map[string]*series)
+
+		for i, item := range items {
+			p, ok := parsePart(item.Title, markers)
+			if !ok {
+				continue
+			}
+			parts[i], isPart[i] = p, true
+
+			g := groups[p.base]
+			if g == nil {
+				g = &series{seen: make(map[int]bool)}
+				groups[p.base] = g
+			}
+			g.total = max(g.total, p.total)
+			g.seen[p.index] = true
+		}
+
+		complete := func(g *series) bool {
+			for x := 1; x <= g.total; x++ {
+				if !g.seen[x] {
+					return false
+				}
+			}
+			return true
+		}
+
+		var order []int
+		for i := range items {
+			if !isPart[i] {
+				order = append(order, i)
+				continue
+			}
+			g := groups[parts[i].base]
+			if !complete(g) {
+				continue
+			}
+			if lastOnly && parts[i].index != g.total {
+				continue
+			}
+			order = append(order, i)
+		}
+		return pick(items, order)
+	}, nil
+}
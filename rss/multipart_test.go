@@ -0,0 +1,150 @@
+package rss
+
+import (
+	"testing"
+
+	"rss-proxy/config"
+)
+
+func TestMultipartCompleteRule(t *testing.T) {
+	feed := RSS{
+		Channel: Channel{
+			Items: []Item{
+				{Title: "[3/3] L'affaire du collier"},
+				{Title: "[2/3] L'affaire du collier"},
+				{Title: "[2/2] La disparition"},
+				{Title: "[1/3] L'affaire du collier"},
+				{Title: "[1/2] Le trésor"},
+				{Title: "Hors série"},
+			},
+		},
+	}
+
+	out := ApplyRules(feed, []config.Rule{{Type: "multipart_complete"}})
+
+	var titles []string
+	for _, item := range out.Channel.Items {
+		titles = append(titles, item.Title)
+	}
+	// "La disparition" misses part 1 and "Le trésor" part 2: both held back.
+	want := []string{
+		"[3/3] L'affaire du collier",
+		"[2/3] L'affaire du collier",
+		"[1/3] L'affaire du collier",
+		"Hors série",
+	}
+	if len(titles) != len(want) {
+		t.Fatalf("expected %v, got %v", want, titles)
+	}
+	for i := range want {
+		if titles[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, titles)
+		}
+	}
+
+	out = ApplyRules(feed, []config.Rule{{Type: "multipart_complete", Keep: "last"}})
+	if len(out.Channel.Items) != 2 || out.Channel.Items[0].Title != "[3/3] L'affaire du collier" {
+		t.Fatalf("expected only the last part and Hors série, got %+v", out.Channel.Items)
+	}
+
+	if _, err := CompileRules("legend", []config.Rule{{Type: "multipart_complete", Keep: "first"}}); err == nil {
+		t.Fatal("expected invalid keep to be rejected")
+	}
+}
+
+func TestMultipartMarkers(t *testing.T) {
+	markers, err := compileMarkers([]string{"common"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string][2]int{
+		"[1/2] L'affaire":           {1, 2},
+		"L'affaire (2/2)":           {2, 2},
+		"L'affaire, Partie 1 sur 3": {1, 3},
+		"The heist - Part 2 of 2":   {2, 2},
+		"Le procès – Épisode 3/4":   {3, 4},
+		"Le procès – épisode 4 / 4": {4, 4},
+	}
+	for title, want := range cases {
+		p, ok := parsePart(title, markers)
+		if !ok || p.index != want[0] || p.total != want[1] {
+			t.Fatalf("%q: expected %v, got %+v (ok=%v)", title, want, p, ok)
+		}
+	}
+
+	// Parts of the same story share a base title whatever the marker position.
+	a, _ := parsePart("Le procès – Épisode 3/4", markers)
+	b, _ := parsePart("Le procès – épisode 4 / 4", markers)
+	if a.base != b.base {
+		t.Fatalf("expected same base, got %q and %q", a.base, b.base)
+	}
+
+	// Episode markers are whole words.
+	for _, title := range []string{"Deep sleep 1/2", "Step 3/4 of the plan", "Keep 2/2"} {
+		if p, ok := parsePart(title, markers); ok {
+			t.Fatalf("%q: unexpected marker %+v", title, p)
+		}
+	}
+	if p, ok := parsePart("Ép. 1/2", markers); !ok || p.index != 1 {
+		t.Fatalf("expected a marker at the start of the title, got %+v (ok=%v)", p, ok)
+	}
+
+	// Only [x/y] is recognised by default.
+	if _, ok := parsePart("L'affaire (2/2)", defaultMarkers); ok {
+		t.Fatal("expected (x/y) to be ignored by default markers")
+	}
+}
+
+func TestTitleFractionEqualsCustomMarker(t *testing.T) {
+	feed := RSS{
+		Channel: Channel{
+			Items: []Item{
+				{Title: "Chapitre 1 de 2"},
+				{Title: "Chapitre 2 de 2"},
+			},
+		},
+	}
+
+	out := ApplyRules(feed, []config.Rule{{
+		Type:    "title_fraction_equals",
+		Markers: []string{`Chapitre (?P<part>\d+) de (?P<total>\d+)`},
+	}})
+	if len(out.Channel.Items) != 1 || out.Channel.Items[0].Title != "Chapitre 2 de 2" {
+		t.Fatalf("expected only the last chapter, got %+v", out.Channel.Items)
+	}
+
+	for _, markers := range [][]string{{"unknown("}, {`Chapitre (\d+) de (\d+)`}} {
+		rule := config.Rule{Type: "multipart_complete", Markers: markers}
+		if _, err := CompileRules("legend", []config.Rule{rule}); err == nil {
+			t.Fatalf("%v: expected an error", markers)
+		}
+	}
+}
+
+func TestCustomMarkerWithoutNumbers(t *testing.T) {
+	feed := RSS{
+		Channel: Channel{
+			Items: []Item{
+				// The optional part group doesn't match.
+				{Title: "Chapitre /2"},
+				// Both groups match empty strings: not 0/0.
+				{Title: "Chapitre /"},
+				// The next marker still applies.
+				{Title: "Chapitre / [2/2]"},
+			},
+		},
+	}
+	markers := []string{`Chapitre (?P<part>\d+)?/(?P<total>\d*)`, "brackets"}
+
+	out := ApplyRules(feed, []config.Rule{{Type: "title_fraction_equals", Markers: markers}})
+	if len(out.Channel.Items) != 1 || out.Channel.Items[0].Title != "Chapitre / [2/2]" {
+		t.Fatalf("expected only the bracketed title, got %+v", out.Channel.Items)
+	}
+
+	// Without a marker, multipart_complete leaves the items alone.
+	out = ApplyRules(feed, []config.Rule{{Type: "multipart_complete", Markers: markers[:1]}})
+	if len(out.Channel.Items) != 3 {
+		t.Fatalf("expected all items, got %+v", out.Channel.Items)
+	}
+}
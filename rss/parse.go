@@ -4,8 +4,26 @@ import "encoding/xml"
 
 // Parse lit le RSS pour appliquer les règles,
 // sans jamais être utilisé pour la sortie XML.
+//
+// Item.Fields est rempli au mieux : si la seconde lecture échoue, les règles
+// sur les champs ne voient aucun champ.
 func Parse(data []byte) (RSS, error) {
+	feed, err := parseItems(data)
+	if err != nil {
+		return feed, err
+	}
+	_ = setItemFields(&feed, data)
+	return feed, nil
+}
+
+// parseItems lit le RSS sans Item.Fields.
+func parseItems(data []byte) (RSS, error) {
 	var feed RSS
-	err := xml.Unmarshal(data, &feed)
-	return feed, err
+	if err := xml.Unmarshal(data, &feed); err != nil {
+		return feed, err
+	}
+	for i := range feed.Channel.Items {
+		feed.Channel.Items[i].index = i
+	}
+	return feed, nil
 }
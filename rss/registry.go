@@ -0,0 +1,88 @@
+package rss
+
+import (
+	"fmt"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"rss-proxy/config"
+)
+
+// RuleFactory compiles a rule of a registered type into a Matcher.
+//
+// node is the rule's YAML mapping as written in the config, including the
+// `type` key and any option the rule defines; decode it with node.Decode.
+// Errors are reported at startup, prefixed with the feed and rule path; so is
+// a nil Matcher without error.
+type RuleFactory func(node *yaml.Node) (Matcher, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]RuleFactory{}
+)
+
+// RegisterRule makes a rule type available to feed configs, alongside the
+// built-in rules (which register the same way).
+//
+// It is meant to be called from an init function, before feeds are compiled.
+// It panics if name is empty, already registered or reserved for a
+// feed-level rule, or if factory is nil.
+func RegisterRule(name string, factory RuleFactory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if name == "" {
+		panic("rss: RegisterRule with an empty name")
+	}
+	if factory == nil {
+		panic(fmt.Sprintf("rss: RegisterRule %q with a nil factory", name))
+	}
+	if _, ok := registry[name]; ok {
+		panic(fmt.Sprintf("rss: rule %q registered twice", name))
+	}
+	if _, ok := selectorCompilers[name]; ok {
+		panic(fmt.Sprintf("rss: rule %q is a feed-level rule", name))
+	}
+	registry[name] = factory
+}
+
+func lookupRule(name string) (RuleFactory, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	factory, ok := registry[name]
+	return factory, ok
+}
+
+// compile runs the factory on the YAML node of rule.
+func (f RuleFactory) compile(rule config.Rule) (Matcher, error) {
+	node, err := ruleNode(rule)
+	if err != nil {
+		return nil, err
+	}
+	return f(node)
+}
+
+// builtinRule adapts a compiler working on config.Rule to a RuleFactory.
+func builtinRule(compile ruleCompiler) RuleFactory {
+	return func(node *yaml.Node) (Matcher, error) {
+		var rule config.Rule
+		if err := node.Decode(&rule); err != nil {
+			return nil, err
+		}
+		return compile(rule)
+	}
+}
+
+// ruleNode returns the YAML node a rule was loaded from. Rules built in code
+// have none: their node is synthesized from the struct.
+func ruleNode(rule config.Rule) (*yaml.Node, error) {
+	if rule.Node != nil {
+		return rule.Node, nil
+	}
+	var node yaml.Node
+	if err := node.Encode(rule); err != nil {
+		return nil, err
+	}
+	return &node, nil
+}
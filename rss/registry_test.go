@@ -0,0 +1,119 @@
+package rss
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+
+	"rss-proxy/config"
+)
+
+func init() {
+	// test_title_chars_max keeps items whose title is at most `chars` characters:
+	// an option config.Rule doesn't know about.
+	RegisterRule("test_title_chars_max", func(node *yaml.Node) (Matcher, error) {
+		var opts struct {
+			Chars int `yaml:"chars"`
+		}
+		if err := node.Decode(&opts); err != nil {
+			return nil, err
+		}
+		if opts.Chars <= 0 {
+			return nil, errors.New("missing chars")
+		}
+		return func(item Item, _ *EvalContext) (bool, error) {
+			return utf8.RuneCountInString(item.Title) <= opts.Chars, nil
+		}, nil
+	})
+	RegisterRule("test_nil_matcher", func(*yaml.Node) (Matcher, error) { return nil, nil })
+}
+
+func TestRegisteredRule(t *testing.T) {
+	var feed config.Feed
+	err := yaml.Unmarshal([]byte(`
+id: custom
+rules:
+  - any:
+      - type: test_title_chars_max
+        chars: 8
+      - type: title_contains
+        value: keep
+`), &feed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rs, err := CompileFeed(feed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := rs.Apply(RSS{Channel: Channel{Items: []Item{
+		{Title: "Short"},
+		{Title: "A much longer title"},
+		{Title: "Long title, but keep it"},
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Channel.Items) != 2 || out.Channel.Items[1].Title != "Long title, but keep it" {
+		t.Fatalf("unexpected items: %+v", out.Channel.Items)
+	}
+}
+
+func TestRegisteredRuleError(t *testing.T) {
+	_, err := CompileRules("custom", []config.Rule{{Type: "test_title_chars_max"}})
+	if err == nil || !strings.Contains(err.Error(), `feed "custom": rules[0]: test_title_chars_max: missing chars`) {
+		t.Fatalf("expected factory error, got %v", err)
+	}
+}
+
+func TestRegisteredRuleNilMatcher(t *testing.T) {
+	_, err := CompileRules("custom", []config.Rule{{Not: &config.Rule{Type: "test_nil_matcher"}}})
+	if err == nil || !strings.Contains(err.Error(), `feed "custom": rules[0].not: test_nil_matcher: rule factory returned a nil matcher`) {
+		t.Fatalf("expected nil matcher error, got %v", err)
+	}
+}
+
+func TestBuiltinRuleReadsStruct(t *testing.T) {
+	cfg, err := config.Parse([]byte(`
+feeds:
+  - id: legend
+    rules:
+      - type: title_contains
+        value: foo
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Embedders may adjust rules after Parse: built-in rules see the change.
+	cfg.Feeds[0].Rules[0].Value = "bar"
+
+	rs, err := CompileFeed(cfg.Feeds[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := rs.Apply(RSS{Channel: Channel{Items: []Item{{Title: "foo"}, {Title: "bar"}}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Channel.Items) != 1 || out.Channel.Items[0].Title != "bar" {
+		t.Fatalf("expected only bar, got %+v", out.Channel.Items)
+	}
+}
+
+func TestRegisterRulePanics(t *testing.T) {
+	factory := func(*yaml.Node) (Matcher, error) { return nil, nil }
+	for _, name := range []string{"", "title_contains", "keep_latest"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("RegisterRule(%q) should panic", name)
+				}
+			}()
+			RegisterRule(name, factory)
+		}()
+	}
+}
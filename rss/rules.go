@@ -1,171 +1,800 @@
 package rss
 
 import (
+	"errors"
+	"fmt"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"rss-proxy/config"
 )
 
-// Matches patterns like [1/2], [2/2], [10/10]
-var reFraction = regexp.MustCompile(`\[(\d+)\s*/\s*(\d+)\]`)
+// defaultEpisodePattern extracts the episode number from the title when
+// <itunes:episode> is missing and the feed sets no episode_pattern.
+var defaultEpisodePattern = regexp.MustCompile(`\b(?P<episode>\d{3,4})\b`)
 
-// ApplyRules filters RSS items according to the configured rules.
-func ApplyRules(feed RSS, rules []config.Rule) RSS {
+// Matcher is a compiled rule: it reports whether an item is kept.
+//
+// A non-nil error reports a runtime data problem with the item (for example
+// an unparseable duration); the feed's on_rule_error policy decides its fate.
+type Matcher func(item Item, ctx *EvalContext) (bool, error)
+
+// EvalContext carries the state shared by every matcher during one evaluation.
+type EvalContext struct {
+	// now is the evaluation time, read once per Apply.
+	now time.Time
+	// loc is the feed timezone used by calendar rules (weekday, hour).
+	loc *time.Location
+	// episodePattern extracts episode numbers from titles.
+	episodePattern *regexp.Regexp
+
+	// nextVisible is the earliest time an item hidden by a delay rule
+	// becomes visible, set by apply; zero if none is.
+	nextVisible time.Time
+}
+
+// Now returns the evaluation time, the same for every item of a feed.
+func (ctx *EvalContext) Now() time.Time { return ctx.now }
+
+// Location returns the feed timezone (UTC unless the feed sets one).
+func (ctx *EvalContext) Location() *time.Location { return ctx.loc }
+
+// hideUntil records that an item is hidden until t.
+func (ctx *EvalContext) hideUntil(t time.Time) {
+	if ctx.nextVisible.IsZero() || t.Before(ctx.nextVisible) {
+		ctx.nextVisible = t
+	}
+}
+
+// ruleCompiler turns a config rule of a given type into a matcher.
+type ruleCompiler func(rule config.Rule) (Matcher, error)
+
+func init() {
+	builtins := map[string]ruleCompiler{
+		"length_max":             compileLengthMax,
+		"length_min":             compileLengthMin,
+		"length_between":         compileLengthBetween,
+		"title_contains":         containsRule(itemTitle),
+		"title_excludes":         excludesRule(itemTitle),
+		"title_regex":            regexRule(itemTitle),
+		"description_contains":   containsRule(itemDescription),
+		"description_excludes":   excludesRule(itemDescription),
+		"description_regex":      regexRule(itemDescription),
+		"episode_number_min":     compileEpisodeNumberMin,
+		"episode_number_max":     compileEpisodeNumberMax,
+		"episode_number_between": compileEpisodeNumberBetween,
+		"title_fraction_equals":  compileTitleFractionEquals,
+		"published_after":        compilePublishedAfter,
+		"published_before":       compilePublishedBefore,
+		"max_age":                compileMaxAge,
+		"delay":                  compileDelay,
+		"weekday_in":             compileWeekdayIn,
+		"published_hour_between": compilePublishedHourBetween,
+		"enclosure_type":         compileEnclosureType,
+		"enclosure_size_min":     compileEnclosureSizeMin,
+		"enclosure_size_max":     compileEnclosureSizeMax,
+		"has_enclosure":          compileHasEnclosure,
+		"episode_type":           compileEpisodeType,
+		"season_min":             compileSeasonMin,
+		"season_max":             compileSeasonMax,
+		"season_in":              compileSeasonIn,
+		"category_in":            compileCategoryIn,
+		"category_not_in":        compileCategoryNotIn,
+		"keyword_contains":       compileKeywordContains,
+		"author_contains":        containsRule(itemAuthors),
+		"author_excludes":        excludesRule(itemAuthors),
+		"field_regex":            compileFieldRegex,
+		"guid_regex":             regexRule(itemGUID),
+		"link_regex":             regexRule(itemLink),
+	}
+	for name, compile := range builtins {
+		RegisterRule(name, builtinRule(compile))
+		builtinCompilers[name] = compile
+	}
+}
+
+// builtinCompilers holds the rule types registered by this package, as
+// opposed to library users. They compile from the config.Rule struct, which
+// callers may change after config.Parse: only registered factories read the
+// YAML node.
+var builtinCompilers = map[string]ruleCompiler{}
+
+// Values of config.Feed.OnRuleError.
+const (
+	// OnRuleErrorKeep treats a rule that can't be evaluated as matched (default).
+	OnRuleErrorKeep = "keep"
+	// OnRuleErrorDrop drops items a rule can't be evaluated on.
+	OnRuleErrorDrop = "drop"
+	// OnRuleErrorFail fails the whole request.
+	OnRuleErrorFail = "fail"
+)
+
+// Values of config.Feed.Mode.
+const (
+	// ModeAll keeps items matched by every rule (default).
+	ModeAll = "all"
+	// ModeFirstMatch lets the first matching rule decide, through its action.
+	ModeFirstMatch = "first_match"
+)
+
+// Values of config.Rule.Action and config.Feed.DefaultAction.
+const (
+	ActionKeep = "keep"
+	ActionDrop = "drop"
+)
+
+// RuleSet is the compiled, immutable form of a feed's rules.
+//
+// It is built once at startup by CompileFeed and is safe for concurrent use.
+type RuleSet struct {
+	feedID      string
+	onRuleError string
+
+	// firstMatch enables first_match mode, where defaultKeep decides the
+	// items no rule matched.
+	firstMatch  bool
+	defaultKeep bool
+
+	// matchers are the per-item rules, selectors the feed-level rules
+	// run over the remaining items afterwards.
+	matchers  []indexed[Matcher]
+	selectors []indexed[selector]
+
+	// needsFields is set when a rule reads Item.Fields, which the handler
+	// only collects then.
+	needsFields bool
+
+	loc            *time.Location
+	episodePattern *regexp.Regexp
+
+	now func() time.Time
+}
+
+// CompileFeed validates the feed rules and compiles them into a RuleSet.
+//
+// Errors name the feed ID and the rule index, e.g.
+// `feed "legend": rules[2]: title_regex: ...`.
+func CompileFeed(feed config.Feed) (*RuleSet, error) {
+	rs := &RuleSet{
+		feedID:         feed.ID,
+		onRuleError:    feed.OnRuleError,
+		now:            time.Now,
+		loc:            time.UTC,
+		episodePattern: defaultEpisodePattern,
+	}
+
+	if tz := strings.TrimSpace(feed.Timezone); tz != "" {
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			return nil, fmt.Errorf("feed %q: invalid timezone %q: %w", feed.ID, tz, err)
+		}
+		rs.loc = loc
+	}
+
+	if p := feed.EpisodePattern; p != "" {
+		re, err := compileEpisodePattern(p)
+		if err != nil {
+			return nil, fmt.Errorf("feed %q: invalid episode_pattern: %w", feed.ID, err)
+		}
+		rs.episodePattern = re
+	}
+
+	if feed.Use != nil {
+		return nil, fmt.Errorf("feed %q: rule sets in use must be expanded first (config.Parse)", feed.ID)
+	}
+
+	switch rs.onRuleError {
+	case "":
+		rs.onRuleError = OnRuleErrorKeep
+	case OnRuleErrorKeep, OnRuleErrorDrop, OnRuleErrorFail:
+	default:
+		return nil, fmt.Errorf("feed %q: invalid on_rule_error %q (want keep, drop or fail)", feed.ID, feed.OnRuleError)
+	}
+
+	switch feed.Mode {
+	case "", ModeAll:
+		if feed.DefaultAction != "" {
+			return nil, fmt.Errorf("feed %q: default_action requires mode: %s", feed.ID, ModeFirstMatch)
+		}
+	case ModeFirstMatch:
+		rs.firstMatch = true
+		switch feed.DefaultAction {
+		case "", ActionKeep:
+			rs.defaultKeep = true
+		case ActionDrop:
+		default:
+			return nil, fmt.Errorf("feed %q: invalid default_action %q (want keep or drop)", feed.ID, feed.DefaultAction)
+		}
+	default:
+		return nil, fmt.Errorf("feed %q: invalid mode %q (want all or first_match)", feed.ID, feed.Mode)
+	}
+
+	for i, rule := range feed.Rules {
+		path := rulePath(fmt.Sprintf("rules[%d]", i), rule)
+
+		if compile, ok := selectorCompilers[rule.Type]; ok {
+			if rule.Action != "" {
+				return nil, fmt.Errorf("feed %q: %s: %s: feed-level rules take no action", feed.ID, path, rule.Type)
+			}
+			sel, err := compile(rule)
+			if err != nil {
+				return nil, fmt.Errorf("feed %q: %s: %s: %w", feed.ID, path, rule.Type, err)
+			}
+			rs.selectors = append(rs.selectors, indexed[selector]{index: i, path: path, rule: rule, fn: sel})
+			continue
+		}
+
+		var keep bool
+		switch {
+		case !rs.firstMatch && rule.Action != "":
+			return nil, fmt.Errorf("feed %q: %s: action requires mode: %s", feed.ID, path, ModeFirstMatch)
+		case !rs.firstMatch:
+		case rule.Action == ActionKeep:
+			keep = true
+		case rule.Action == ActionDrop:
+		case rule.Action == "":
+			return nil, fmt.Errorf("feed %q: %s: missing action (keep or drop)", feed.ID, path)
+		default:
+			return nil, fmt.Errorf("feed %q: %s: invalid action %q (want keep or drop)", feed.ID, path, rule.Action)
+		}
+
+		m, err := compileRule(path, rule)
+		if err != nil {
+			return nil, fmt.Errorf("feed %q: %w", feed.ID, err)
+		}
+		im := indexed[Matcher]{index: i, path: path, rule: rule, fn: m, keep: keep}
+		// Compiled above: the options are valid.
+		switch {
+		case rule.Type == "delay":
+			im.visibleAt, _ = delayVisibility(rule)
+		case rule.Not != nil && rule.Not.Type == "delay":
+			// The first_match way to hide items: {not: {type: delay}, action: drop}.
+			im.visibleAt, _ = delayVisibility(*rule.Not)
+		}
+		rs.matchers = append(rs.matchers, im)
+		if usesFields(rule) {
+			rs.needsFields = true
+		}
+	}
+	return rs, nil
+}
+
+// indexed pairs a compiled rule with its config and index in the feed rules.
+type indexed[T any] struct {
+	index int
+	// path locates the rule in the configuration, as in errors.
+	path string
+	rule config.Rule
+	fn   T
+	// keep is the rule action in first_match mode.
+	keep bool
+	// visibleAt is, for delay rules and their negation, when the rule
+	// result changes for an item.
+	visibleAt func(Item) (time.Time, error)
+}
+
+// CompileRules is a shorthand for CompileFeed with default feed options.
+func CompileRules(feedID string, rules []config.Rule) (*RuleSet, error) {
+	return CompileFeed(config.Feed{ID: feedID, Rules: rules})
+}
+
+// MustCompileRules is like CompileRules but panics if the rules are invalid.
+func MustCompileRules(feedID string, rules []config.Rule) *RuleSet {
+	rs, err := CompileRules(feedID, rules)
+	if err != nil {
+		panic(err)
+	}
+	return rs
+}
+
+// Apply filters RSS items, keeping those matched by every rule, or in
+// first_match mode those whose first matching rule has action keep.
+//
+// Per-item rules run first; feed-level rules (keep_latest, skip_oldest)
+// then run in order over the remaining items. It only returns an error when
+// a rule can't be evaluated on an item and the feed policy is
+// on_rule_error: fail.
+func (rs *RuleSet) Apply(feed RSS) (RSS, error) {
+	filtered, _, err := rs.apply(feed)
+	return filtered, err
+}
+
+// apply is Apply, also returning the evaluation context for callers
+// interested in its outcome (next item visibility).
+func (rs *RuleSet) apply(feed RSS) (RSS, *EvalContext, error) {
 	filtered := RSS{
 		Channel: Channel{
 			Title: feed.Channel.Title,
 		},
 	}
+	ctx := rs.newContext()
 
-ITEM:
+	var hidden []delayedItem
 	for _, item := range feed.Channel.Items {
-		for _, rule := range rules {
-			if !matchRule(item, rule) {
-				continue ITEM
-			}
+		decidedBy, keep, err := rs.matchItem(item, ctx)
+		if err != nil && rs.onRuleError == OnRuleErrorFail {
+			return RSS{}, nil, fmt.Errorf("feed %q: rules[%d]: item %q: %w", rs.feedID, decidedBy.index, item.Title, err)
+		}
+		if keep {
+			filtered.Channel.Items = append(filtered.Channel.Items, item)
+			continue
+		}
+		// Items a rule failed on stay dropped.
+		if err != nil {
+			continue
+		}
+		if visible, ok := rs.shownAt(item, ctx); ok {
+			hidden = append(hidden, delayedItem{item: item, at: len(filtered.Channel.Items), visible: visible})
+		}
+	}
+
+	matched := filtered.Channel.Items
+	for _, sel := range rs.selectors {
+		filtered.Channel.Items = sel.fn(filtered.Channel.Items, ctx)
+	}
+
+	for _, d := range hidden {
+		later := *ctx
+		later.now = d.visible
+		if rs.survivesSelectors(matched, d, &later) {
+			ctx.hideUntil(d.visible)
 		}
-		filtered.Channel.Items = append(filtered.Channel.Items, item)
 	}
 
-	return filtered
+	return filtered, ctx, nil
 }
 
-func matchRule(item Item, rule config.Rule) bool {
-	title := item.Title
+// shownAt returns when an item the per-item rules dropped gets kept, if a
+// top-level delay rule hides it until then. The rules are evaluated again at
+// each time a delay rule result changes for the item, in either mode: only
+// then may a delay change the decision.
+func (rs *RuleSet) shownAt(item Item, ctx *EvalContext) (time.Time, bool) {
+	var times []time.Time
+	for _, m := range rs.matchers {
+		if m.visibleAt == nil {
+			continue
+		}
+		if visible, err := m.visibleAt(item); err == nil && visible.After(ctx.now) {
+			times = append(times, visible)
+		}
+	}
+	slices.SortFunc(times, time.Time.Compare)
+
+	later := *ctx
+	for _, visible := range times {
+		later.now = visible
+		if _, keep, _ := rs.matchItem(item, &later); keep {
+			return visible, true
+		}
+	}
+	return time.Time{}, false
+}
 
-	switch rule.Type {
+// delayedItem is an item hidden by a delay rule until visible. at is its
+// position among the items the per-item rules kept.
+type delayedItem struct {
+	item    Item
+	at      int
+	visible time.Time
+}
 
-	case "length_max":
-		// Keep items whose iTunes duration is <= the configured max.
-		// Supported formats for both item.Duration and rule.Value:
-		//   - "SS" (seconds)
-		//   - "MM:SS"
-		//   - "HH:MM:SS"
-		maxSec, ok := parseITunesDurationToSeconds(rule.Value)
-		if !ok {
-			// Invalid config: ignore the rule (keep the item).
-			Logger.Warn("Can't parse duration")
+// survivesSelectors reports whether the feed-level rules would keep d among
+// the matched items, once visible: an item they would drop anyway (e.g. a
+// duplicate) doesn't make the feed change.
+func (rs *RuleSet) survivesSelectors(matched []Item, d delayedItem, ctx *EvalContext) bool {
+	if len(rs.selectors) == 0 {
+		return true
+	}
+	// Selectors return subsets of their input: items are tracked by position.
+	items := make([]Item, 0, len(matched)+1)
+	items = append(items, matched[:d.at]...)
+	items = append(items, d.item)
+	items = append(items, matched[d.at:]...)
+	for i := range items {
+		items[i].index = i
+	}
+	for _, sel := range rs.selectors {
+		items = sel.fn(items, ctx)
+	}
+	for _, item := range items {
+		if item.index == d.at {
 			return true
 		}
-		if strings.TrimSpace(item.Duration) == "" {
-			// Some feeds don't provide duration; in that case, don't drop items.
-			Logger.Warn("No duration provided")
-			return true
+	}
+	return false
+}
+
+func (rs *RuleSet) newContext() *EvalContext {
+	return &EvalContext{now: rs.now(), loc: rs.loc, episodePattern: rs.episodePattern}
+}
+
+// matchItem runs the per-item rules on item and reports whether it is kept,
+// along with the rule that decided: the first one rejecting it, or in
+// first_match mode the first one matching it. decidedBy is nil when no rule
+// decided (every rule matched, or none did in first_match mode).
+//
+// err is the rule error that decided, under the drop and fail policies;
+// under keep, rule errors are logged and the rule counts as matched, except
+// in first_match mode where the item is kept.
+func (rs *RuleSet) matchItem(item Item, ctx *EvalContext) (decidedBy *indexed[Matcher], keep bool, err error) {
+	for i := range rs.matchers {
+		m := &rs.matchers[i]
+		ok, err := m.fn(item, ctx)
+		if err != nil {
+			switch rs.onRuleError {
+			case OnRuleErrorFail:
+				return m, false, err
+			case OnRuleErrorDrop:
+				Logger.Warn("rule error, dropping item",
+					"feed_id", rs.feedID,
+					"rule", m.index,
+					"title", item.Title,
+					"error", err,
+				)
+				return m, false, err
+			default:
+				Logger.Warn("rule error, keeping item",
+					"feed_id", rs.feedID,
+					"rule", m.index,
+					"title", item.Title,
+					"error", err,
+				)
+				if rs.firstMatch {
+					return m, true, err
+				}
+				ok = true
+			}
+		}
+
+		if rs.firstMatch {
+			if ok {
+				return m, m.keep, nil
+			}
+			continue
 		}
-		durSec, ok := parseITunesDurationToSeconds(item.Duration)
 		if !ok {
-			// Unparseable duration: keep the item.
-			Logger.Warn("Can't parse duration")
-			return true
+			return m, false, nil
 		}
-		return durSec <= maxSec
+	}
 
-	case "title_contains":
-		return strings.Contains(strings.ToUpper(title), strings.ToUpper(rule.Value))
+	if rs.firstMatch {
+		return nil, rs.defaultKeep, nil
+	}
+	return nil, true, nil
+}
 
-	case "title_excludes":
-		return !strings.Contains(strings.ToUpper(title), strings.ToUpper(rule.Value))
+// ApplyRules filters RSS items according to the configured rules.
+//
+// It compiles rules on every call and panics if they are invalid;
+// long-lived callers should use CompileFeed once and RuleSet.Apply.
+// Items a rule can't be evaluated on are kept.
+func ApplyRules(feed RSS, rules []config.Rule) RSS {
+	// The default on_rule_error policy (keep) never returns an error.
+	out, _ := MustCompileRules("", rules).Apply(feed)
+	return out
+}
 
-	case "title_regex":
-		re := regexp.MustCompile(rule.Value)
-		return re.MatchString(title)
+// compileRule compiles a single rule. path locates the rule in error messages,
+// unless the rule knows its origin in the configuration.
+func compileRule(path string, rule config.Rule) (Matcher, error) {
+	path = rulePath(path, rule)
+	groups := 0
+	for _, set := range []bool{rule.All != nil, rule.Any != nil, rule.Not != nil, rule.Expr != ""} {
+		if set {
+			groups++
+		}
+	}
+	if groups > 1 {
+		return nil, fmt.Errorf("%s: only one of all, any, not or expr is allowed per rule", path)
+	}
+	if rule.Use != nil {
+		return nil, fmt.Errorf("%s: rule sets in use must be expanded first (config.Parse)", path)
+	}
+	if groups == 1 && rule.Type != "" {
+		return nil, fmt.Errorf("%s: a group or expr rule can't also have a type (%q)", path, rule.Type)
+	}
 
-	case "episode_number_min":
-		if item.Episode > 0 {
-			return item.Episode >= rule.Min
+	switch {
+	case rule.Expr != "":
+		m, err := compileExprRule(rule)
+		if err != nil {
+			return nil, fmt.Errorf("%s: expr: %w", path, err)
 		}
+		return m, nil
 
-		// Fallback: extract episode number from title
-		re := regexp.MustCompile(`\b(\d{3,4})\b`)
-		m := re.FindStringSubmatch(title)
-		if m == nil {
-			return false
+	case rule.All != nil:
+		ms, err := compileGroup(path+".all", rule.All)
+		if err != nil {
+			return nil, err
 		}
+		return func(item Item, ctx *EvalContext) (bool, error) {
+			for _, m := range ms {
+				if ok, err := m(item, ctx); err != nil || !ok {
+					return false, err
+				}
+			}
+			return true, nil
+		}, nil
 
-		n, _ := strconv.Atoi(m[1])
-		return n >= rule.Min
+	case rule.Any != nil:
+		ms, err := compileGroup(path+".any", rule.Any)
+		if err != nil {
+			return nil, err
+		}
+		return func(item Item, ctx *EvalContext) (bool, error) {
+			for _, m := range ms {
+				if ok, err := m(item, ctx); err != nil || ok {
+					return ok, err
+				}
+			}
+			return false, nil
+		}, nil
 
-	case "title_fraction_equals":
-		// Keep only items where [x/y] and x == y
-		m := reFraction.FindStringSubmatch(title)
-		if m == nil {
-			return false
+	case rule.Not != nil:
+		if rule.Not.Action != "" {
+			return nil, fmt.Errorf("%s: action is only allowed on top-level rules", rulePath(path+".not", *rule.Not))
+		}
+		m, err := compileRule(path+".not", *rule.Not)
+		if err != nil {
+			return nil, err
 		}
+		return func(item Item, ctx *EvalContext) (bool, error) {
+			ok, err := m(item, ctx)
+			if err != nil {
+				return false, err
+			}
+			return !ok, nil
+		}, nil
+	}
 
-		x, _ := strconv.Atoi(m[1])
-		y, _ := strconv.Atoi(m[2])
+	if rule.Type == "" {
+		return nil, fmt.Errorf("%s: missing rule type", path)
+	}
+	if _, ok := selectorCompilers[rule.Type]; ok {
+		return nil, fmt.Errorf("%s: %s can only be used at the top level of a feed's rules", path, rule.Type)
+	}
 
-		return x == y
+	factory, ok := lookupRule(rule.Type)
+	if !ok {
+		return nil, fmt.Errorf("%s: unknown rule type %q", path, rule.Type)
+	}
 
-	default:
-		return true
+	compile, ok := builtinCompilers[rule.Type]
+	if !ok {
+		compile = factory.compile
 	}
+	m, err := compile(rule)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s: %w", path, rule.Type, err)
+	}
+	if m == nil {
+		return nil, fmt.Errorf("%s: %s: rule factory returned a nil matcher", path, rule.Type)
+	}
+	return m, nil
 }
 
-// parseITunesDurationToSeconds parses common iTunes duration formats.
-//
-// iTunes duration can be either:
-//   - integer seconds ("1234")
-//   - "MM:SS"
-//   - "HH:MM:SS" (or "H:MM:SS")
-//
-// Returns (seconds, true) on success, (0, false) otherwise.
-func parseITunesDurationToSeconds(s string) (int, bool) {
-	s = strings.TrimSpace(s)
-	if s == "" {
-		return 0, false
+// rulePath returns the origin of a rule loaded by config.Parse, path for a
+// rule built in code.
+func rulePath(path string, rule config.Rule) string {
+	if rule.Origin != "" {
+		return rule.Origin
 	}
+	return path
+}
 
-	if !strings.Contains(s, ":") {
-		n, err := strconv.Atoi(s)
-		if err != nil || n < 0 {
-			return 0, false
+func compileGroup(path string, rules []config.Rule) ([]Matcher, error) {
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("%s: empty group", path)
+	}
+	ms := make([]Matcher, 0, len(rules))
+	for i, r := range rules {
+		if r.Action != "" {
+			return nil, fmt.Errorf("%s: action is only allowed on top-level rules", rulePath(fmt.Sprintf("%s[%d]", path, i), r))
+		}
+		m, err := compileRule(fmt.Sprintf("%s[%d]", path, i), r)
+		if err != nil {
+			return nil, err
 		}
-		return n, true
+		ms = append(ms, m)
+	}
+	return ms, nil
+}
+
+// requireValue rejects rules missing their `value` field.
+func requireValue(rule config.Rule) error {
+	if strings.TrimSpace(rule.Value) == "" {
+		return errors.New("missing value")
 	}
+	return nil
+}
 
-	parts := strings.Split(s, ":")
-	if len(parts) < 2 || len(parts) > 3 {
-		return 0, false
+// ruleValues returns the rule `value` and `values` as a single list,
+// rejecting rules that set neither.
+func ruleValues(rule config.Rule) ([]string, error) {
+	var out []string
+	if v := strings.TrimSpace(rule.Value); v != "" {
+		out = append(out, v)
+	}
+	for _, v := range rule.Values {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
 	}
+	if len(out) == 0 {
+		return nil, errors.New("missing value or values")
+	}
+	return out, nil
+}
+
+// textField extracts the text a string rule matches against.
+type textField func(Item) string
+
+func itemTitle(item Item) string { return item.Title }
 
-	toInt := func(p string) (int, bool) {
-		p = strings.TrimSpace(p)
-		if p == "" {
-			return 0, false
+func itemGUID(item Item) string { return strings.TrimSpace(item.GUID.Value) }
+
+func itemLink(item Item) string { return strings.TrimSpace(item.Link) }
+
+// itemAuthors returns every credited author (<itunes:author>, <author>,
+// <dc:creator>), one per line.
+func itemAuthors(item Item) string {
+	var authors []string
+	for _, a := range []string{item.ITunesAuthor, item.Author, item.Creator} {
+		if a = strings.TrimSpace(a); a != "" {
+			authors = append(authors, a)
 		}
-		n, err := strconv.Atoi(p)
-		if err != nil || n < 0 {
-			return 0, false
+	}
+	return strings.Join(authors, "\n")
+}
+
+// itemDescription returns the show notes as plain text (HTML stripped,
+// entities decoded).
+func itemDescription(item Item) string { return plainText(item.Description) }
+
+// containsRule builds a compiler keeping items whose field contains the value
+// (case-insensitive unless case_sensitive is set).
+func containsRule(field textField) ruleCompiler {
+	return func(rule config.Rule) (Matcher, error) {
+		if err := requireValue(rule); err != nil {
+			return nil, err
 		}
-		return n, true
+		opts, err := compileTextOptions(rule, false)
+		if err != nil {
+			return nil, err
+		}
+		value := opts.prepare(rule.Value)
+		return func(item Item, _ *EvalContext) (bool, error) {
+			return strings.Contains(opts.prepare(field(item)), value), nil
+		}, nil
 	}
+}
 
-	if len(parts) == 2 {
-		mm, ok := toInt(parts[0])
-		if !ok {
-			return 0, false
+// excludesRule builds a compiler dropping items whose field contains the value
+// (case-insensitive unless case_sensitive is set).
+func excludesRule(field textField) ruleCompiler {
+	return func(rule config.Rule) (Matcher, error) {
+		if err := requireValue(rule); err != nil {
+			return nil, err
 		}
-		ss, ok := toInt(parts[1])
+		opts, err := compileTextOptions(rule, false)
+		if err != nil {
+			return nil, err
+		}
+		value := opts.prepare(rule.Value)
+		return func(item Item, _ *EvalContext) (bool, error) {
+			return !strings.Contains(opts.prepare(field(item)), value), nil
+		}, nil
+	}
+}
+
+// regexRule builds a compiler keeping items whose field matches the regex
+// (case-sensitive unless case_sensitive is false).
+func regexRule(field textField) ruleCompiler {
+	return func(rule config.Rule) (Matcher, error) {
+		if err := requireValue(rule); err != nil {
+			return nil, err
+		}
+		opts, err := compileTextOptions(rule, true)
+		if err != nil {
+			return nil, err
+		}
+		re, err := opts.compileRegexp(rule.Value)
+		if err != nil {
+			return nil, err
+		}
+		return func(item Item, _ *EvalContext) (bool, error) {
+			return re.MatchString(opts.fold(field(item))), nil
+		}, nil
+	}
+}
+
+func compileEpisodeNumberMin(rule config.Rule) (Matcher, error) {
+	if rule.Min <= 0 {
+		return nil, errors.New("missing min (must be > 0)")
+	}
+	return compileEpisodeRange(rule, rule.Min, -1)
+}
+
+func compileEpisodeNumberMax(rule config.Rule) (Matcher, error) {
+	if rule.Max <= 0 {
+		return nil, errors.New("missing max (must be > 0)")
+	}
+	return compileEpisodeRange(rule, -1, rule.Max)
+}
+
+func compileEpisodeNumberBetween(rule config.Rule) (Matcher, error) {
+	if rule.Min <= 0 || rule.Max <= 0 {
+		return nil, errors.New("missing min or max (must be > 0)")
+	}
+	if rule.Min > rule.Max {
+		return nil, fmt.Errorf("min %d is greater than max %d", rule.Min, rule.Max)
+	}
+	return compileEpisodeRange(rule, rule.Min, rule.Max)
+}
+
+// compileEpisodeRange builds a matcher keeping items whose episode number is
+// within [minN, maxN]; a negative bound is open. Items without a number
+// follow the rule `missing` policy: drop (default) or keep.
+func compileEpisodeRange(rule config.Rule, minN, maxN int) (Matcher, error) {
+	var keepMissing bool
+	switch rule.Missing {
+	case "", "drop":
+	case "keep":
+		keepMissing = true
+	default:
+		return nil, fmt.Errorf("invalid missing %q (want keep or drop)", rule.Missing)
+	}
+
+	return func(item Item, ctx *EvalContext) (bool, error) {
+		n, ok := episodeNumber(item, ctx)
 		if !ok {
-			return 0, false
+			return keepMissing, nil
+		}
+		if minN >= 0 && n < minN {
+			return false, nil
 		}
-		return mm*60 + ss, true
+		if maxN >= 0 && n > maxN {
+			return false, nil
+		}
+		return true, nil
+	}, nil
+}
+
+// compileEpisodePattern compiles a feed episode_pattern, which must capture
+// the number in a group named episode.
+func compileEpisodePattern(pattern string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
 	}
+	if re.SubexpIndex("episode") < 0 {
+		return nil, fmt.Errorf("%q has no (?P<episode>...) group", pattern)
+	}
+	return re, nil
+}
 
-	hh, ok := toInt(parts[0])
-	if !ok {
-		return 0, false
+// episodeNumber returns <itunes:episode>, falling back to the number
+// extracted from the title by the feed episode pattern.
+func episodeNumber(item Item, ctx *EvalContext) (int, bool) {
+	if item.Episode > 0 {
+		return item.Episode, true
 	}
-	mm, ok := toInt(parts[1])
-	if !ok {
+
+	re := ctx.episodePattern
+	if re == nil {
+		re = defaultEpisodePattern
+	}
+	m := re.FindStringSubmatch(item.Title)
+	if m == nil {
 		return 0, false
 	}
-	ss, ok := toInt(parts[2])
-	if !ok {
+
+	n, err := strconv.Atoi(m[re.SubexpIndex("episode")])
+	if err != nil {
 		return 0, false
 	}
-	return hh*3600 + mm*60 + ss, true
+	return n, true
 }
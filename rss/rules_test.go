@@ -1,6 +1,7 @@
 package rss
 
 import (
+	"strings"
 	"testing"
 
 	"rss-proxy/config"
@@ -31,6 +32,69 @@ func TestEpisodeNumberMinRule(t *testing.T) {
 	}
 }
 
+func TestEpisodePatternAndRangeRules(t *testing.T) {
+	feed := RSS{
+		Channel: Channel{
+			Items: []Item{
+				{Title: "S02E05 – Le retour"},
+				{Title: "S02E12 – Finale"},
+				{Title: "Bonus 2023"},
+				{Title: "Jour 7", Episode: 7},
+			},
+		},
+	}
+
+	run := func(pattern string, rule config.Rule) []string {
+		t.Helper()
+		rs, err := CompileFeed(config.Feed{ID: "show", EpisodePattern: pattern, Rules: []config.Rule{rule}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := rs.Apply(feed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var titles []string
+		for _, item := range out.Channel.Items {
+			titles = append(titles, item.Title)
+		}
+		return titles
+	}
+
+	got := run(`S\d+E(?P<episode>\d+)`, config.Rule{Type: "episode_number_between", Min: 5, Max: 10})
+	if strings.Join(got, "|") != "S02E05 – Le retour|Jour 7" {
+		t.Fatalf("between: unexpected items %q", got)
+	}
+
+	got = run(`S\d+E(?P<episode>\d+)`, config.Rule{Type: "episode_number_max", Max: 7, Missing: "keep"})
+	if strings.Join(got, "|") != "S02E05 – Le retour|Bonus 2023|Jour 7" {
+		t.Fatalf("max with missing keep: unexpected items %q", got)
+	}
+
+	// Without a pattern, the default grabs 4-digit numbers such as years.
+	got = run("", config.Rule{Type: "episode_number_min", Min: 100})
+	if strings.Join(got, "|") != "Bonus 2023" {
+		t.Fatalf("default pattern: unexpected items %q", got)
+	}
+}
+
+func TestEpisodeRulesValidation(t *testing.T) {
+	cases := map[string]config.Feed{
+		"no (?P<episode>...) group": {EpisodePattern: `S\d+E(\d+)`},
+		"invalid episode_pattern":   {EpisodePattern: `(?P<episode>`},
+		"min 9 is greater than max": {Rules: []config.Rule{{Type: "episode_number_between", Min: 9, Max: 2}}},
+		"missing max":               {Rules: []config.Rule{{Type: "episode_number_max"}}},
+		`invalid missing "skip"`:    {Rules: []config.Rule{{Type: "episode_number_min", Min: 1, Missing: "skip"}}},
+	}
+	for want, feed := range cases {
+		feed.ID = "show"
+		_, err := CompileFeed(feed)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected error containing %q, got %v", want, err)
+		}
+	}
+}
+
 func TestTitleContainsRule(t *testing.T) {
 	feed := RSS{
 		Channel: Channel{
@@ -145,3 +209,170 @@ func TestLengthMaxRule(t *testing.T) {
 		t.Fatal("wrong third item kept")
 	}
 }
+
+func TestBooleanGroupRules(t *testing.T) {
+	feed := RSS{
+		Channel: Channel{
+			Items: []Item{
+				{Title: "[1/2] Affaire A"},
+				{Title: "[2/2] Affaire A"},
+				{Title: "[REDIFF] [2/2] Affaire B"},
+				{Title: "Hors série"},
+			},
+		},
+	}
+
+	// Keep [1/2] OR [2/2], but NOT [REDIFF].
+	rules := []config.Rule{
+		{Any: []config.Rule{
+			{Type: "title_contains", Value: "[1/2]"},
+			{Type: "title_contains", Value: "[2/2]"},
+		}},
+		{Not: &config.Rule{Type: "title_contains", Value: "[REDIFF]"}},
+	}
+
+	out := ApplyRules(feed, rules)
+
+	if len(out.Channel.Items) != 2 {
+		t.Fatalf("expected 2 items kept, got %d", len(out.Channel.Items))
+	}
+	if out.Channel.Items[0].Title != "[1/2] Affaire A" {
+		t.Fatal("wrong first item kept")
+	}
+	if out.Channel.Items[1].Title != "[2/2] Affaire A" {
+		t.Fatal("wrong second item kept")
+	}
+}
+
+func TestCompileRulesRejectsInvalidRegex(t *testing.T) {
+	_, err := CompileRules("legend", []config.Rule{
+		{Type: "title_contains", Value: "KEEP"},
+		{Any: []config.Rule{
+			{Type: "title_regex", Value: "[unclosed"},
+		}},
+	})
+	if err == nil {
+		t.Fatal("expected an error for invalid regex")
+	}
+	if !strings.Contains(err.Error(), `feed "legend"`) || !strings.Contains(err.Error(), "rules[1].any[0]") {
+		t.Fatalf("error should name feed and rule index, got %q", err)
+	}
+}
+
+func TestCompileRulesRejectsInvalidDuration(t *testing.T) {
+	_, err := CompileRules("legend", []config.Rule{
+		{Type: "length_max", Value: "one hour"},
+	})
+	if err == nil {
+		t.Fatal("expected an error for invalid duration")
+	}
+	if !strings.Contains(err.Error(), "rules[0]") {
+		t.Fatalf("error should name rule index, got %q", err)
+	}
+}
+
+func TestCompileRulesRejectsUnknownTypeAndMissingFields(t *testing.T) {
+	cases := map[string]config.Rule{
+		"unknown type":  {Type: "titel_contains", Value: "KEEP"},
+		"missing value": {Type: "title_contains"},
+		"missing min":   {Type: "episode_number_min"},
+		"empty group":   {Any: []config.Rule{}},
+		"typed group":   {Type: "title_contains", Value: "X", Not: &config.Rule{Type: "title_contains", Value: "Y"}},
+	}
+
+	for name, rule := range cases {
+		if _, err := CompileRules("legend", []config.Rule{rule}); err == nil {
+			t.Fatalf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestOnRuleErrorPolicy(t *testing.T) {
+	feed := RSS{
+		Channel: Channel{
+			Items: []Item{
+				{Title: "Short", Duration: "10:00"},
+				{Title: "Broken", Duration: "n/a"},
+			},
+		},
+	}
+	rules := []config.Rule{{Type: "length_max", Value: "3600"}}
+
+	keep, err := CompileFeed(config.Feed{ID: "legend", Rules: rules})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := keep.Apply(feed)
+	if err != nil || len(out.Channel.Items) != 2 {
+		t.Fatalf("keep: expected 2 items and no error, got %d, %v", len(out.Channel.Items), err)
+	}
+
+	drop, err := CompileFeed(config.Feed{ID: "legend", Rules: rules, OnRuleError: OnRuleErrorDrop})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err = drop.Apply(feed)
+	if err != nil || len(out.Channel.Items) != 1 || out.Channel.Items[0].Title != "Short" {
+		t.Fatalf("drop: expected only Short, got %+v, %v", out.Channel.Items, err)
+	}
+
+	fail, err := CompileFeed(config.Feed{ID: "legend", Rules: rules, OnRuleError: OnRuleErrorFail})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fail.Apply(feed); err == nil {
+		t.Fatal("fail: expected an error")
+	}
+
+	if _, err := CompileFeed(config.Feed{ID: "legend", OnRuleError: "ignore"}); err == nil {
+		t.Fatal("expected invalid on_rule_error to be rejected")
+	}
+}
+
+func TestDescriptionRules(t *testing.T) {
+	feed := RSS{
+		Channel: Channel{
+			Items: []Item{
+				{Title: "Épisode 1", Description: "<p>Avec <b>Marie</b> Curie</p>"},
+				{Title: "Épisode 2", Description: "<p>Une <em>rediffusion</em> avec Marie&nbsp;Curie</p>"},
+				{Title: "Épisode 3", Description: "Avec Pierre Curie"},
+			},
+		},
+	}
+
+	out := ApplyRules(feed, []config.Rule{
+		{Type: "description_contains", Value: "marie curie"},
+		{Type: "description_excludes", Value: "rediffusion"},
+	})
+	if len(out.Channel.Items) != 1 || out.Channel.Items[0].Title != "Épisode 1" {
+		t.Fatalf("expected only Épisode 1, got %+v", out.Channel.Items)
+	}
+
+	out = ApplyRules(feed, []config.Rule{
+		{Type: "description_regex", Value: `^Avec \w+ Curie$`},
+	})
+	if len(out.Channel.Items) != 2 {
+		t.Fatalf("expected 2 items kept, got %d", len(out.Channel.Items))
+	}
+}
+
+func TestAuthorRules(t *testing.T) {
+	feed := RSS{
+		Channel: Channel{
+			Items: []Item{
+				{Title: "A", ITunesAuthor: "Jane Doe"},
+				{Title: "B", Author: "jane.doe@example.com (Jane Doe)"},
+				{Title: "C", Creator: "John Smith"},
+				{Title: "D", ITunesAuthor: "Jane Doe & John Smith"},
+			},
+		},
+	}
+
+	out := ApplyRules(feed, []config.Rule{
+		{Type: "author_contains", Value: "jane doe"},
+		{Type: "author_excludes", Value: "John Smith"},
+	})
+	if len(out.Channel.Items) != 2 || out.Channel.Items[0].Title != "A" || out.Channel.Items[1].Title != "B" {
+		t.Fatalf("expected A and B, got %+v", out.Channel.Items)
+	}
+}
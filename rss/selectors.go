@@ -0,0 +1,222 @@
+package rss
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"rss-proxy/config"
+)
+
+// selector is a compiled feed-level rule: it looks at the whole list of items
+// left by the per-item rules and returns the ones to keep, in feed order.
+type selector func(items []Item, ctx *EvalContext) []Item
+
+// selectorCompiler turns a config rule of a given type into a selector.
+type selectorCompiler func(rule config.Rule) (selector, error)
+
+// selectorCompilers maps feed-level rule types to their compilers.
+var selectorCompilers = map[string]selectorCompiler{
+	"keep_latest": compileKeepLatest,
+	"skip_oldest": compileSkipOldest,
+	// skip_first is an alias of skip_oldest.
+	"skip_first": compileSkipOldest,
+	"dedupe":     compileDedupe,
+
+	"multipart_complete": compileMultipartComplete,
+}
+
+// dedupeKeys extract the identity of an episode for the dedupe rule.
+// An empty key means the item is never considered a duplicate.
+var dedupeKeys = map[string]func(Item) string{
+	"guid": func(item Item) string {
+		return strings.TrimSpace(item.GUID.Value)
+	},
+	"enclosure_url": func(item Item) string {
+		if !hasEnclosure(item) {
+			return ""
+		}
+		return strings.TrimSpace(item.Enclosure.URL)
+	},
+	"normalized_title": func(item Item) string {
+		return normalizeTitleKey(item.Title)
+	},
+}
+
+func compileKeepLatest(rule config.Rule) (selector, error) {
+	// Keep the `count` most recent items.
+	newestFirst, err := compileRecencyOrder(rule)
+	if err != nil {
+		return nil, err
+	}
+	return func(items []Item, ctx *EvalContext) []Item {
+		order := newestFirst(items, ctx)
+		if len(order) > rule.Count {
+			order = order[:rule.Count]
+		}
+		return pick(items, order)
+	}, nil
+}
+
+func compileSkipOldest(rule config.Rule) (selector, error) {
+	// Drop the `count` oldest items.
+	newestFirst, err := compileRecencyOrder(rule)
+	if err != nil {
+		return nil, err
+	}
+	return func(items []Item, ctx *EvalContext) []Item {
+		order := newestFirst(items, ctx)
+		if len(order) > rule.Count {
+			order = order[:len(order)-rule.Count]
+		} else {
+			order = nil
+		}
+		return pick(items, order)
+	}, nil
+}
+
+// compileRecencyOrder validates count / by and returns a function giving
+// item indexes from newest to oldest.
+//
+// Items without a usable key (no pubDate, no episode number) are considered
+// the oldest; ties keep feed order.
+func compileRecencyOrder(rule config.Rule) (func([]Item, *EvalContext) []int, error) {
+	if rule.Count <= 0 {
+		return nil, errors.New("missing count (must be > 0)")
+	}
+
+	var key func(Item, *EvalContext) (int64, bool)
+	switch rule.By {
+	case "", "pub_date":
+		key = func(item Item, _ *EvalContext) (int64, bool) {
+			t, err := parsePubDate(item.PubDate)
+			if err != nil {
+				return 0, false
+			}
+			return t.Unix(), true
+		}
+	case "episode":
+		key = func(item Item, ctx *EvalContext) (int64, bool) {
+			n, ok := episodeNumber(item, ctx)
+			return int64(n), ok
+		}
+	default:
+		return nil, fmt.Errorf("invalid by %q (want pub_date or episode)", rule.By)
+	}
+
+	return func(items []Item, ctx *EvalContext) []int {
+		type entry struct {
+			index int
+			key   int64
+			ok    bool
+		}
+		entries := make([]entry, len(items))
+		for i, item := range items {
+			k, ok := key(item, ctx)
+			entries[i] = entry{index: i, key: k, ok: ok}
+		}
+		sort.SliceStable(entries, func(a, b int) bool {
+			if entries[a].ok != entries[b].ok {
+				return entries[a].ok
+			}
+			return entries[a].key > entries[b].key
+		})
+
+		order := make([]int, len(entries))
+		for i, e := range entries {
+			order[i] = e.index
+		}
+		return order
+	}, nil
+}
+
+// pick returns the items at the given indexes, in feed order.
+func pick(items []Item, indexes []int) []Item {
+	keep := make([]bool, len(items))
+	for _, i := range indexes {
+		keep[i] = true
+	}
+
+	var out []Item
+	for i, item := range items {
+		if keep[i] {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+func compileDedupe(rule config.Rule) (selector, error) {
+	// Keep a single copy of episodes sharing the same key.
+	key, ok := dedupeKeys[rule.Key]
+	if !ok {
+		return nil, fmt.Errorf("invalid key %q (want guid, enclosure_url or normalized_title)", rule.Key)
+	}
+
+	var latest bool
+	switch rule.Keep {
+	case "", "first":
+	case "latest":
+		latest = true
+	default:
+		return nil, fmt.Errorf("invalid keep %q (want first or latest)", rule.Keep)
+	}
+
+	return func(items []Item, _ *EvalContext) []Item {
+		// chosen maps a key to the index of the copy kept so far.
+		chosen := make(map[string]int)
+		var order []int
+		for i, item := range items {
+			k := key(item)
+			if k == "" {
+				order = append(order, i)
+				continue
+			}
+			j, seen := chosen[k]
+			if !seen {
+				chosen[k] = i
+				order = append(order, i)
+				continue
+			}
+			if latest && newerThan(item, items[j]) {
+				chosen[k] = i
+			}
+		}
+
+		// Replace each first occurrence by the copy finally chosen.
+		for n, i := range order {
+			if k := key(items[i]); k != "" {
+				order[n] = chosen[k]
+			}
+		}
+		return pick(items, order)
+	}, nil
+}
+
+// newerThan reports whether a was published strictly after b.
+// Items without a parseable pubDate are never newer.
+func newerThan(a, b Item) bool {
+	ta, err := parsePubDate(a.PubDate)
+	if err != nil {
+		return false
+	}
+	tb, err := parsePubDate(b.PubDate)
+	if err != nil {
+		return true
+	}
+	return ta.After(tb)
+}
+
+// normalizeTitleKey lowercases a title and keeps only letters and digits,
+// so that copies differing in case, spacing or punctuation share a key.
+func normalizeTitleKey(title string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(title) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,103 @@
+package rss
+
+import (
+	"testing"
+
+	"rss-proxy/config"
+)
+
+func TestKeepLatestByPubDate(t *testing.T) {
+	feed := RSS{
+		Channel: Channel{
+			Items: []Item{
+				{Title: "Mercredi", PubDate: "Wed, 04 Dec 2024 06:00:00 +0000"},
+				{Title: "Undated"},
+				{Title: "Vendredi", PubDate: "Fri, 06 Dec 2024 06:00:00 +0000"},
+				{Title: "[REDIFF] Jeudi", PubDate: "Thu, 05 Dec 2024 06:00:00 +0000"},
+				{Title: "Mardi", PubDate: "Tue, 03 Dec 2024 06:00:00 +0000"},
+			},
+		},
+	}
+
+	// Per-item rules run first: the rerun doesn't count towards keep_latest.
+	out := ApplyRules(feed, []config.Rule{
+		{Type: "keep_latest", Count: 2},
+		{Type: "title_excludes", Value: "REDIFF"},
+	})
+
+	if len(out.Channel.Items) != 2 {
+		t.Fatalf("expected 2 items kept, got %d", len(out.Channel.Items))
+	}
+	// Feed order is preserved.
+	if out.Channel.Items[0].Title != "Mercredi" || out.Channel.Items[1].Title != "Vendredi" {
+		t.Fatalf("wrong items kept: %+v", out.Channel.Items)
+	}
+}
+
+func TestSkipOldestByEpisode(t *testing.T) {
+	feed := RSS{
+		Channel: Channel{
+			Items: []Item{
+				{Title: "Jour 3", Episode: 3},
+				{Title: "Jour 1", Episode: 1},
+				{Title: "Jour 2", Episode: 2},
+			},
+		},
+	}
+
+	out := ApplyRules(feed, []config.Rule{
+		{Type: "skip_oldest", Count: 2, By: "episode"},
+	})
+
+	if len(out.Channel.Items) != 1 || out.Channel.Items[0].Title != "Jour 3" {
+		t.Fatalf("expected only Jour 3, got %+v", out.Channel.Items)
+	}
+}
+
+func TestFeedLevelRulesValidation(t *testing.T) {
+	cases := map[string]config.Rule{
+		"missing count": {Type: "keep_latest"},
+		"invalid by":    {Type: "keep_latest", Count: 5, By: "title"},
+		"nested":        {Not: &config.Rule{Type: "keep_latest", Count: 5}},
+	}
+
+	for name, rule := range cases {
+		if _, err := CompileRules("legend", []config.Rule{rule}); err == nil {
+			t.Fatalf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestDedupeRule(t *testing.T) {
+	feed := RSS{
+		Channel: Channel{
+			Items: []Item{
+				{Title: "Episode 2 (republished)", GUID: GUID{Value: "ep-2"}, PubDate: "Fri, 06 Dec 2024 06:00:00 +0000"},
+				{Title: "Episode 1", GUID: GUID{Value: "ep-1"}, PubDate: "Tue, 03 Dec 2024 06:00:00 +0000"},
+				{Title: "Episode 2", GUID: GUID{Value: "ep-2"}, PubDate: "Wed, 04 Dec 2024 06:00:00 +0000"},
+				{Title: "No GUID"},
+				{Title: "No GUID"},
+			},
+		},
+	}
+
+	out := ApplyRules(feed, []config.Rule{{Type: "dedupe", Key: "guid"}})
+	if len(out.Channel.Items) != 4 || out.Channel.Items[0].Title != "Episode 2 (republished)" {
+		t.Fatalf("expected first copy of ep-2 kept, got %+v", out.Channel.Items)
+	}
+
+	feed.Channel.Items[0].PubDate = "Mon, 02 Dec 2024 06:00:00 +0000"
+	out = ApplyRules(feed, []config.Rule{{Type: "dedupe", Key: "guid", Keep: "latest"}})
+	if len(out.Channel.Items) != 4 || out.Channel.Items[1].Title != "Episode 2" {
+		t.Fatalf("expected latest copy of ep-2 kept, got %+v", out.Channel.Items)
+	}
+
+	out = ApplyRules(feed, []config.Rule{{Type: "dedupe", Key: "normalized_title"}})
+	if len(out.Channel.Items) != 4 {
+		t.Fatalf("expected No GUID deduplicated by title, got %+v", out.Channel.Items)
+	}
+
+	if _, err := CompileRules("legend", []config.Rule{{Type: "dedupe", Key: "title"}}); err == nil {
+		t.Fatal("expected invalid key to be rejected")
+	}
+}
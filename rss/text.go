@@ -0,0 +1,29 @@
+package rss
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	// <script> and <style> blocks whose content is not text.
+	reHTMLBlocks = regexp.MustCompile(`(?is)<(script|style)\b.*?</(script|style)\s*>`)
+	// Block-level tags and line breaks, replaced by a space so words don't merge.
+	reHTMLBreaks = regexp.MustCompile(`(?i)</?(p|br|div|li|ul|ol|h[1-6]|tr|td|blockquote)\b[^>]*>`)
+	// Tags and comments only: "a < b and c > d" is text.
+	reHTMLTags = regexp.MustCompile(`(?s)<!--.*?-->|</?[A-Za-z][^>]*>`)
+)
+
+// plainText turns an HTML fragment (typically <description> show notes) into
+// plain text: tags are stripped, entities decoded and whitespace collapsed.
+//
+// Escaped markup (&lt;p&gt;) and CDATA sections are already decoded by
+// xml.Unmarshal, so both forms of show notes end up as HTML here.
+func plainText(s string) string {
+	s = reHTMLBlocks.ReplaceAllString(s, " ")
+	s = reHTMLBreaks.ReplaceAllString(s, " ")
+	s = reHTMLTags.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,20 @@
+package rss
+
+import "testing"
+
+func TestPlainText(t *testing.T) {
+	cases := map[string]string{
+		"<p>Hello <strong>world</strong></p>":                    "Hello world",
+		"Avec notre invit&eacute;&nbsp;: <b>Jean</b>":            "Avec notre invité : Jean",
+		"Line one<br/>Line two":                                  "Line one Line two",
+		"<style>p { color: red }</style><p>Fish &amp; Chips</p>": "Fish & Chips",
+		"a < b and c > d":                                        "a < b and c > d",
+		"<!-- ad slot --><p>x<3 &amp; 5>2</p>":                   "x<3 & 5>2",
+	}
+
+	for in, want := range cases {
+		if got := plainText(in); got != want {
+			t.Fatalf("plainText(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package rss
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+
+	"rss-proxy/config"
+)
+
+// Unicode normalization and accent folding for string-matching rules:
+// "invité" matches whether the feed encodes "é" as one code point (NFC) or as
+// "e" + U+0301 (NFD).
+
+// foldSpecial maps letters without a canonical decomposition to their
+// closest unaccented spelling, for accent folding.
+var foldSpecial = map[rune]string{
+	'ß': "ss", 'ẞ': "SS",
+	'æ': "ae", 'Æ': "AE",
+	'œ': "oe", 'Œ': "OE",
+	'ø': "o", 'Ø': "O",
+	'ł': "l", 'Ł': "L",
+	'đ': "d", 'Đ': "D",
+	'ð': "d", 'Ð': "D",
+	'þ': "th", 'Þ': "TH",
+	'ı': "i",
+}
+
+// toNFD returns s in Unicode canonical decomposition.
+func toNFD(s string) string { return norm.NFD.String(s) }
+
+// toNFC returns s in Unicode canonical composition.
+func toNFC(s string) string { return norm.NFC.String(s) }
+
+// stripMarks decomposes text, drops the nonspacing marks and recomposes what
+// remains.
+var stripMarks = transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+
+// foldAccents removes diacritics: "Épisode spécial" becomes "Episode special".
+func foldAccents(s string) string {
+	folded, _, err := transform.String(stripMarks, s)
+	if err != nil {
+		folded = s
+	}
+	var b strings.Builder
+	for _, r := range folded {
+		if f, ok := foldSpecial[r]; ok {
+			b.WriteString(f)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// textOptions controls how string-matching rules compare text.
+type textOptions struct {
+	caseSensitive bool
+	foldAccents   bool
+	normalize     func(string) string
+	// nfd is set when normalize decomposes, which regexes don't support.
+	nfd bool
+}
+
+// compileTextOptions reads the case_sensitive, fold_accents and normalize
+// options of a rule. caseSensitive is the rule type default, used when the
+// rule doesn't set case_sensitive.
+func compileTextOptions(rule config.Rule, caseSensitive bool) (textOptions, error) {
+	opts := textOptions{
+		caseSensitive: caseSensitive,
+		foldAccents:   rule.FoldAccents,
+	}
+	if rule.CaseSensitive != nil {
+		opts.caseSensitive = *rule.CaseSensitive
+	}
+
+	switch strings.ToLower(rule.Normalize) {
+	case "", "nfc":
+		opts.normalize = toNFC
+	case "nfd":
+		opts.normalize = toNFD
+		opts.nfd = true
+	default:
+		return opts, fmt.Errorf("invalid normalize %q (want nfc or nfd)", rule.Normalize)
+	}
+	return opts, nil
+}
+
+// fold normalizes s and folds accents if configured, keeping case.
+func (o textOptions) fold(s string) string {
+	s = o.normalize(s)
+	if o.foldAccents {
+		s = foldAccents(s)
+	}
+	return s
+}
+
+// prepare turns s into its comparable form: fold plus case folding.
+func (o textOptions) prepare(s string) string {
+	s = o.fold(s)
+	if !o.caseSensitive {
+		s = strings.ToUpper(s)
+	}
+	return s
+}
+
+// compileRegexp compiles a rule regex; match it against fold(s).
+//
+// The pattern is not normalized, which would split the letters of character
+// classes such as [éè] in NFD: it must be written in NFC, the default form of
+// the text it matches. Accents are folded on both sides.
+func (o textOptions) compileRegexp(pattern string) (*regexp.Regexp, error) {
+	if o.nfd {
+		return nil, errors.New("normalize: nfd is not supported by regexes")
+	}
+	if o.foldAccents {
+		pattern = foldAccents(pattern)
+	}
+	if !o.caseSensitive {
+		pattern = "(?i)" + pattern
+	}
+	return regexp.Compile(pattern)
+}
@@ -0,0 +1,176 @@
+package rss
+
+import (
+	"strings"
+	"testing"
+
+	"rss-proxy/config"
+)
+
+func TestNormalizationForms(t *testing.T) {
+	composed := "Invité spécial à Noël"
+	decomposed := "Invite\u0301 spe\u0301cial a\u0300 Noe\u0308l"
+
+	if got := toNFD(composed); got != decomposed {
+		t.Fatalf("toNFD: got %q, want %q", got, decomposed)
+	}
+	if got := toNFC(decomposed); got != composed {
+		t.Fatalf("toNFC: got %q, want %q", got, composed)
+	}
+	if got := toNFC(composed); got != composed {
+		t.Fatalf("toNFC should keep composed text, got %q", got)
+	}
+
+	// Several marks, in any order: canonical ordering applies.
+	for in, want := range map[string]string{
+		"Vie\u0323\u0302t": "Việt",
+		"Vie\u0302\u0323t": "Việt",
+		"u\u0308\u0304":    "ǖ",
+	} {
+		if got := toNFC(in); got != want {
+			t.Fatalf("toNFC(%q) = %q, want %q", in, got, want)
+		}
+		if got := toNFD(want); got != toNFD(in) {
+			t.Fatalf("toNFD(%q) = %q, want %q", want, got, toNFD(in))
+		}
+	}
+}
+
+func TestFoldAccents(t *testing.T) {
+	cases := map[string]string{
+		"Épisode spécial":      "Episode special",
+		"Invite\u0301":         "Invite",
+		"Cœur de Łódź":         "Coeur de Lodz",
+		"Straße":               "Strasse",
+		"naïve façade":         "naive facade",
+		"Tiếng Việt":           "Tieng Viet",
+		"ǖ":                    "u",
+		"plain ASCII, 100% ok": "plain ASCII, 100% ok",
+	}
+	for in, want := range cases {
+		if got := foldAccents(in); got != want {
+			t.Fatalf("foldAccents(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestTextMatchingOptions(t *testing.T) {
+	yes, no := true, false
+	feed := RSS{
+		Channel: Channel{
+			Items: []Item{
+				{Title: "Jour 640 – Invité : Marc"},
+				{Title: "Jour 641 – Invite\u0301 : Paul"}, // NFD
+				{Title: "Jour 642 – invite special"},
+				{Title: "Jour 643 – solo"},
+			},
+		},
+	}
+
+	tests := []struct {
+		name string
+		rule config.Rule
+		want []string
+	}{
+		{
+			name: "default ignores case, not accents",
+			rule: config.Rule{Type: "title_contains", Value: "INVITÉ"},
+			want: []string{"Jour 640 – Invité : Marc", "Jour 641 – Invite\u0301 : Paul"},
+		},
+		{
+			name: "case sensitive",
+			rule: config.Rule{Type: "title_contains", Value: "invite", CaseSensitive: &yes},
+			want: []string{"Jour 642 – invite special"},
+		},
+		{
+			name: "fold accents",
+			rule: config.Rule{Type: "title_contains", Value: "invite", FoldAccents: true},
+			want: []string{"Jour 640 – Invité : Marc", "Jour 641 – Invite\u0301 : Paul", "Jour 642 – invite special"},
+		},
+		{
+			name: "nfd",
+			rule: config.Rule{Type: "title_excludes", Value: "Invité", Normalize: "nfd"},
+			want: []string{"Jour 642 – invite special", "Jour 643 – solo"},
+		},
+		{
+			name: "regex stays case-sensitive",
+			rule: config.Rule{Type: "title_regex", Value: `invit`},
+			want: []string{"Jour 642 – invite special"},
+		},
+		{
+			name: "regex ignoring case and accents",
+			rule: config.Rule{Type: "title_regex", Value: `Invite :`, CaseSensitive: &no, FoldAccents: true},
+			want: []string{"Jour 640 – Invité : Marc", "Jour 641 – Invite\u0301 : Paul"},
+		},
+		{
+			name: "expr",
+			rule: config.Rule{Expr: `contains(title, "invite") && !matches(title, "special")`, FoldAccents: true},
+			want: []string{"Jour 640 – Invité : Marc", "Jour 641 – Invite\u0301 : Paul"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := MustCompileRules("", []config.Rule{tt.rule}).Apply(feed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, item := range out.Channel.Items {
+				got = append(got, item.Title)
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCategoryAndKeywordFoldAccents(t *testing.T) {
+	feed, err := Parse([]byte(networkRSS))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := ApplyRules(feed, []config.Rule{
+		{Type: "keyword_contains", Value: "economie", FoldAccents: true},
+	})
+	if len(out.Channel.Items) != 1 || out.Channel.Items[0].Title != "Morning 1" {
+		t.Fatalf("unexpected items: %+v", out.Channel.Items)
+	}
+
+	yes := true
+	out = ApplyRules(feed, []config.Rule{
+		{Type: "category_in", Value: "news", CaseSensitive: &yes},
+	})
+	if len(out.Channel.Items) != 0 {
+		t.Fatalf("case-sensitive category_in should match nothing, got %+v", out.Channel.Items)
+	}
+}
+
+func TestInvalidNormalize(t *testing.T) {
+	_, err := CompileRules("legend", []config.Rule{
+		{Type: "title_contains", Value: "x", Normalize: "nfkc"},
+	})
+	if err == nil || !strings.Contains(err.Error(), `invalid normalize "nfkc"`) {
+		t.Fatalf("expected invalid normalize error, got %v", err)
+	}
+}
+
+func TestRegexCharacterClass(t *testing.T) {
+	feed := RSS{Channel: Channel{Items: []Item{
+		{Title: "é"},
+		{Title: "e\u0301"}, // NFD
+		{Title: "e"},
+	}}}
+
+	out := ApplyRules(feed, []config.Rule{{Type: "title_regex", Value: "^[éè]$"}})
+	if len(out.Channel.Items) != 2 || out.Channel.Items[1].Title != "e\u0301" {
+		t.Fatalf("expected both spellings of é, got %+v", out.Channel.Items)
+	}
+
+	_, err := CompileRules("legend", []config.Rule{{Type: "title_regex", Value: "^[éè]$", Normalize: "nfd"}})
+	if err == nil || !strings.Contains(err.Error(), "nfd is not supported") {
+		t.Fatalf("expected nfd to be rejected, got %v", err)
+	}
+}